@@ -0,0 +1,123 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/htojiddinov77-png/Articles/internal/middleware"
+	"github.com/htojiddinov77-png/Articles/internal/store"
+	"github.com/htojiddinov77-png/Articles/internal/tokens"
+	"github.com/htojiddinov77-png/Articles/internal/utils"
+)
+
+// apiKeyPrefixLength is how much of a key is kept to identify it in lists.
+const apiKeyPrefixLength = 10
+
+type APIKeyHandler struct {
+	apiKeyStore store.APIKeyStore
+	logger      *log.Logger
+}
+
+func NewAPIKeyHandler(apiKeyStore store.APIKeyStore, logger *log.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyStore: apiKeyStore,
+		logger:      logger,
+	}
+}
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// HandleCreateAPIKey creates a key for the current user. The plaintext key
+// is only part of this response.
+func (h *APIKeyHandler) HandleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req createAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "name is required and must be at most 100 characters"})
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "expires_at must be in the future"})
+		return
+	}
+
+	user := middleware.GetUser(r)
+	scopes, err := store.ValidateAPIKeyScopes(req.Scopes, user.Permissions)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	plaintext, hash, err := tokens.GenerateAPIKey()
+	if err != nil {
+		h.logger.Printf("ERROR: generating API key: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	key := &store.APIKey{
+		UserID:    user.ID,
+		Name:      req.Name,
+		Prefix:    plaintext[:apiKeyPrefixLength],
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	if err := h.apiKeyStore.CreateAPIKey(key, hash); err != nil {
+		h.logger.Printf("ERROR: creating API key: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"api_key": key, "key": plaintext})
+}
+
+func (h *APIKeyHandler) HandleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+
+	keys, err := h.apiKeyStore.ListAPIKeys(user.ID)
+	if err != nil {
+		h.logger.Printf("ERROR: listing API keys: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"api_keys": keys})
+}
+
+func (h *APIKeyHandler) HandleDeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	keyID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid API key id"})
+		return
+	}
+
+	user := middleware.GetUser(r)
+	err = h.apiKeyStore.DeleteAPIKey(user.ID, keyID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "API key not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: deleting API key: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusNoContent, nil)
+}
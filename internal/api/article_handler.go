@@ -1,24 +1,35 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
-
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/htojiddinov77-png/Articles/internal/middleware"
 	"github.com/htojiddinov77-png/Articles/internal/store"
 	"github.com/htojiddinov77-png/Articles/internal/utils"
 )
 
 type ArticleHandler struct {
-	articleStore store.ArticleStore
-	logger       *log.Logger
+	articleStore  store.ArticleStore
+	categoryStore store.CategoryStore
+	reviewStore   store.ReviewStore
+	logger        *log.Logger
 }
 
-func NewArticleHandler(articleStore store.ArticleStore, logger *log.Logger) *ArticleHandler {
+func NewArticleHandler(articleStore store.ArticleStore, categoryStore store.CategoryStore, reviewStore store.ReviewStore, logger *log.Logger) *ArticleHandler {
 	return &ArticleHandler{
-		articleStore: articleStore,
-		logger:       logger,
+		articleStore:  articleStore,
+		categoryStore: categoryStore,
+		reviewStore:   reviewStore,
+		logger:        logger,
 	}
 }
 
@@ -37,9 +48,257 @@ func (ah *ArticleHandler) HandlerGetArticleById(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	// unpublished articles are only visible to the people who may edit them
+	if article == nil || !canViewArticle(middleware.GetUser(r), article) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "article not found"})
+		return
+	}
+
+	envelope := utils.Envelope{"article": article}
+	if r.URL.Query().Get("include") == "review_summary" {
+		summary, err := ah.reviewStore.GetRatingSummary(articleID)
+		if err != nil {
+			ah.logger.Printf("ERROR: getRatingSummary: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+			return
+		}
+		envelope["review_summary"] = summary
+	}
+
+	utils.SetETag(w, article.Version)
+	utils.WriteJSON(w, http.StatusOK, envelope)
+}
+
+func (ah *ArticleHandler) HandleGetArticleBySlug(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+	if slug == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid article slug"})
+		return
+	}
+
+	article, err := ah.articleStore.GetArticleBySlug(slug)
+	if err != nil {
+		ah.logger.Printf("ERROR: getArticleBySlug: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	redirect := false
+	if article == nil {
+		// renamed articles keep answering on their old slugs
+		article, err = ah.articleStore.GetArticleByOldSlug(slug)
+		if err != nil {
+			ah.logger.Printf("ERROR: getArticleByOldSlug: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+			return
+		}
+		redirect = true
+	}
+
+	// checked before redirecting, so old slugs don't reveal the current
+	// slug of an article the caller can't see
+	if article == nil || !canViewArticle(middleware.GetUser(r), article) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "article not found"})
+		return
+	}
+
+	if redirect {
+		http.Redirect(w, r, "/articles/by-slug/"+url.PathEscape(article.Slug), http.StatusMovedPermanently)
+		return
+	}
+
+	utils.SetETag(w, article.Version)
 	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"article": article})
 }
 
+func (ah *ArticleHandler) HandleListArticles(w http.ResponseWriter, r *http.Request) {
+	filter, err := readArticleFilter(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	ah.listArticles(w, r, filter, utils.Envelope{})
+}
+
+// listArticles runs the listing for the caller, who only sees published
+// articles and their own unless they can manage every article, and adds
+// the page to envelope.
+func (ah *ArticleHandler) listArticles(w http.ResponseWriter, r *http.Request, filter store.ArticleFilter, envelope utils.Envelope) {
+	user := middleware.GetUser(r)
+	filter.ViewerID = user.ID
+	filter.IncludeAll = user.Can(store.PermissionArticlesManage)
+
+	articles, metadata, err := ah.articleStore.ListArticles(filter)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+			return
+		}
+		ah.logger.Printf("ERROR: listArticles: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	envelope["articles"] = articles
+	envelope["metadata"] = metadata
+	utils.WriteJSON(w, http.StatusOK, envelope)
+}
+
+func (ah *ArticleHandler) HandleSearchArticles(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	q := strings.TrimSpace(qs.Get("q"))
+	if q == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "q is required"})
+		return
+	}
+
+	page, err := utils.ReadIntQuery(qs, "page", 1)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	pageSize, err := utils.ReadIntQuery(qs, "page_size", store.DefaultPageSize)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	if err := store.ValidatePage(page, pageSize); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	results, metadata, err := ah.articleStore.SearchArticles(q, page, pageSize)
+	if err != nil {
+		ah.logger.Printf("ERROR: searchArticles: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"results": results, "metadata": metadata})
+}
+
+func (ah *ArticleHandler) HandleListArticlesByTag(w http.ResponseWriter, r *http.Request) {
+	filter, err := readArticleFilter(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	filter.Tag = store.NormalizeTag(chi.URLParam(r, "name"))
+	if filter.Tag == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid tag name"})
+		return
+	}
+
+	ah.listArticles(w, r, filter, utils.Envelope{"tag": filter.Tag})
+}
+
+func (ah *ArticleHandler) HandleListArticlesByCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid category id"})
+		return
+	}
+
+	filter, err := readArticleFilter(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	category, err := ah.categoryStore.GetCategoryById(categoryID)
+	if err != nil {
+		ah.logger.Printf("ERROR: getCategoryById: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if category == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "category not found"})
+		return
+	}
+
+	filter.CategoryID = &category.ID
+	ah.listArticles(w, r, filter, utils.Envelope{"category": category})
+}
+
+// checkCategory writes a 400 and returns false when the article points at
+// a category that doesn't exist.
+func (ah *ArticleHandler) checkCategory(w http.ResponseWriter, article *store.Article) bool {
+	if article.CategoryID == nil {
+		return true
+	}
+
+	category, err := ah.categoryStore.GetCategoryById(int64(*article.CategoryID))
+	if err != nil {
+		ah.logger.Printf("ERROR: getCategoryById: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+
+	if category == nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "category not found"})
+		return false
+	}
+
+	return true
+}
+
+func readArticleFilter(r *http.Request) (store.ArticleFilter, error) {
+	qs := r.URL.Query()
+	filter := store.ArticleFilter{
+		Sort:   utils.ReadStringQuery(qs, "sort", "-created_at"),
+		Cursor: utils.ReadStringQuery(qs, "cursor", ""),
+		Status: utils.ReadStringQuery(qs, "status", ""),
+		Tag:    store.NormalizeTag(qs.Get("tag")),
+	}
+
+	var err error
+	if filter.Page, err = utils.ReadIntQuery(qs, "page", 1); err != nil {
+		return filter, err
+	}
+	if filter.PageSize, err = utils.ReadIntQuery(qs, "page_size", store.DefaultPageSize); err != nil {
+		return filter, err
+	}
+
+	if qs.Get("author_id") != "" {
+		authorID, err := utils.ReadIntQuery(qs, "author_id", 0)
+		if err != nil {
+			return filter, err
+		}
+		filter.AuthorID = &authorID
+	}
+
+	if qs.Get("category_id") != "" {
+		categoryID, err := utils.ReadIntQuery(qs, "category_id", 0)
+		if err != nil {
+			return filter, err
+		}
+		filter.CategoryID = &categoryID
+	}
+	if filter.IncludeDescendants, err = utils.ReadBoolQuery(qs, "include_descendants", false); err != nil {
+		return filter, err
+	}
+
+	if filter.CreatedAfter, err = utils.ReadTimeQuery(qs, "created_after"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedBefore, err = utils.ReadTimeQuery(qs, "created_before"); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedAfter, err = utils.ReadTimeQuery(qs, "updated_after"); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedBefore, err = utils.ReadTimeQuery(qs, "updated_before"); err != nil {
+		return filter, err
+	}
+
+	return filter, filter.Validate()
+}
+
 func (ah *ArticleHandler) HandlerCreateArticle(w http.ResponseWriter, r *http.Request) {
 	var article store.Article
 	err := json.NewDecoder(r.Body).Decode(&article)
@@ -49,6 +308,30 @@ func (ah *ArticleHandler) HandlerCreateArticle(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// the author is always the caller and new articles always start as drafts
+	// (or scheduled when a publish_at is given), whatever the body says
+	article.AuthorId = middleware.GetUser(r).ID
+	article.Status = store.ArticleStatusDraft
+	article.PublishedAt = nil
+
+	article.Tags, err = store.NormalizeTags(article.Tags)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	if article.PublishAt != nil {
+		if !article.PublishAt.After(time.Now()) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "publish_at must be in the future"})
+			return
+		}
+		article.Status = store.ArticleStatusScheduled
+	}
+
+	if !ah.checkCategory(w, &article) {
+		return
+	}
+
 	createdArticle, err := ah.articleStore.CreateArticle(&article)
 	if err != nil {
 		ah.logger.Printf("ERROR: createArticle: %v", err)
@@ -85,11 +368,22 @@ func (ah *ArticleHandler) HandleUpdateArticleById(w http.ResponseWriter, r *http
 		return
 	}
 
+	if !canModifyArticle(middleware.GetUser(r), existingArticle) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not allowed to update this article"})
+		return
+	}
+
+	if !utils.IfMatch(r, existingArticle.Version) {
+		utils.WritePreconditionFailed(w)
+		return
+	}
+
 	var UpdateArticleRequest struct {
 		Title       *string           `json:"title"`
 		Description *string           `json:"description"`
 		Image       *string           `json:"image"`
-		AuthorID    *int              `json:"author_id"`
+		CategoryID  *int              `json:"category_id"`
+		Tags        *[]string         `json:"tags"`
 		Paragraphs  []store.Paragraph `json:"paragraphs"`
 	}
 
@@ -112,21 +406,41 @@ func (ah *ArticleHandler) HandleUpdateArticleById(w http.ResponseWriter, r *http
 		existingArticle.Image = *UpdateArticleRequest.Image
 	}
 
-	if UpdateArticleRequest.AuthorID != nil {
-		existingArticle.AuthorId = *UpdateArticleRequest.AuthorID
+	// category_id 0 takes the article out of its category
+	if UpdateArticleRequest.CategoryID != nil {
+		existingArticle.CategoryID = UpdateArticleRequest.CategoryID
+		if *UpdateArticleRequest.CategoryID == 0 {
+			existingArticle.CategoryID = nil
+		}
+		if !ah.checkCategory(w, existingArticle) {
+			return
+		}
+	}
+
+	if UpdateArticleRequest.Tags != nil {
+		existingArticle.Tags, err = store.NormalizeTags(*UpdateArticleRequest.Tags)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+			return
+		}
 	}
 
 	if UpdateArticleRequest.Paragraphs != nil {
 		existingArticle.Paragraphs = UpdateArticleRequest.Paragraphs
 	}
 
-	err = ah.articleStore.UpdateArticle(existingArticle)
+	err = ah.articleStore.UpdateArticle(existingArticle, middleware.GetUser(r).ID)
+	if errors.Is(err, store.ErrEditConflict) {
+		utils.WriteEditConflict(w, r)
+		return
+	}
 	if err != nil {
 		ah.logger.Printf("ERROR: updatingArticle: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
 		return
 	}
 
+	utils.SetETag(w, existingArticle.Version)
 	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"article": existingArticle})
 }
 
@@ -150,11 +464,138 @@ func (ah *ArticleHandler) HandleDeleteArticlebyId(w http.ResponseWriter, r *http
 		return
 	}
 
-	err = ah.articleStore.DeleteArticle(articleID)
+	if !canModifyArticle(middleware.GetUser(r), existingArticle) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not allowed to delete this article"})
+		return
+	}
+
+	if !utils.IfMatch(r, existingArticle.Version) {
+		utils.WritePreconditionFailed(w)
+		return
+	}
+
+	err = ah.articleStore.DeleteArticle(articleID, existingArticle.Version)
+	if errors.Is(err, store.ErrEditConflict) {
+		utils.WriteEditConflict(w, r)
+		return
+	}
 	if err != nil {
 		ah.logger.Printf("ERROR: deleteArticle: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to delete article"})
 		return
 	}
-	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "article deleted succesfully"})
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "article moved to trash"})
+}
+
+func (ah *ArticleHandler) HandleRestoreArticle(w http.ResponseWriter, r *http.Request) {
+	articleID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid article id"})
+		return
+	}
+
+	deletedArticle, err := ah.articleStore.GetDeletedArticleById(articleID)
+	if err != nil {
+		ah.logger.Printf("ERROR: getDeletedArticleById: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if deletedArticle == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "article not found in trash"})
+		return
+	}
+
+	if !canModifyArticle(middleware.GetUser(r), deletedArticle) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not allowed to restore this article"})
+		return
+	}
+
+	err = ah.articleStore.RestoreArticle(articleID)
+	if err == sql.ErrNoRows {
+		// restored by someone else in the meantime
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "article not found in trash"})
+		return
+	}
+	if err != nil {
+		ah.logger.Printf("ERROR: restoreArticle: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.SetETag(w, deletedArticle.Version)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"article": deletedArticle})
+}
+
+func (ah *ArticleHandler) HandleSubmitArticle(w http.ResponseWriter, r *http.Request) {
+	ah.changeArticleStatus(w, r, store.ArticleStatusInReview)
+}
+
+func (ah *ArticleHandler) HandlePublishArticle(w http.ResponseWriter, r *http.Request) {
+	ah.changeArticleStatus(w, r, store.ArticleStatusPublished)
+}
+
+func (ah *ArticleHandler) HandleUnpublishArticle(w http.ResponseWriter, r *http.Request) {
+	ah.changeArticleStatus(w, r, store.ArticleStatusDraft)
+}
+
+func (ah *ArticleHandler) HandleArchiveArticle(w http.ResponseWriter, r *http.Request) {
+	ah.changeArticleStatus(w, r, store.ArticleStatusArchived)
+}
+
+func (ah *ArticleHandler) HandleScheduleArticle(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PublishAt *time.Time `json:"publish_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if req.PublishAt == nil || !req.PublishAt.After(time.Now()) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "publish_at must be in the future"})
+		return
+	}
+
+	ah.changeArticleStatus(w, r, store.ArticleStatusScheduled, func(article *store.Article) {
+		article.PublishAt = req.PublishAt
+	})
+}
+
+// changeArticleStatus moves the article in the URL to status. The optional
+// before funcs run after the transition is validated and before it is saved.
+func (ah *ArticleHandler) changeArticleStatus(w http.ResponseWriter, r *http.Request, status string, before ...func(*store.Article)) {
+	article := ah.loadEditableArticle(w, r)
+	if article == nil {
+		return
+	}
+
+	if !utils.IfMatch(r, article.Version) {
+		utils.WritePreconditionFailed(w)
+		return
+	}
+
+	if !article.CanTransitionTo(status) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": fmt.Sprintf("article cannot move from %s to %s", article.Status, status)})
+		return
+	}
+
+	article.TransitionTo(status)
+	for _, fn := range before {
+		fn(article)
+	}
+
+	err := ah.articleStore.UpdateArticleStatus(article)
+	if errors.Is(err, store.ErrEditConflict) {
+		utils.WriteEditConflict(w, r)
+		return
+	}
+	if err != nil {
+		ah.logger.Printf("ERROR: updateArticleStatus: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.SetETag(w, article.Version)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"article": article})
 }
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/htojiddinov77-png/Articles/internal/middleware"
+	"github.com/htojiddinov77-png/Articles/internal/store"
+	"github.com/htojiddinov77-png/Articles/internal/utils"
+)
+
+// writeParagraphError maps the store errors shared by every paragraph
+// endpoint to responses.
+func (ah *ArticleHandler) writeParagraphError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrParagraphNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "paragraph not found"})
+	case errors.Is(err, store.ErrParagraphMismatch):
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+	case errors.Is(err, store.ErrEditConflict):
+		utils.WriteEditConflict(w, r)
+	default:
+		ah.logger.Printf("ERROR: paragraph: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+	}
+}
+
+func findParagraph(article *store.Article, paragraphID int) *store.Paragraph {
+	for i := range article.Paragraphs {
+		if article.Paragraphs[i].ID == paragraphID {
+			return &article.Paragraphs[i]
+		}
+	}
+	return nil
+}
+
+func (ah *ArticleHandler) HandleCreateParagraph(w http.ResponseWriter, r *http.Request) {
+	article := ah.loadEditableArticle(w, r)
+	if article == nil {
+		return
+	}
+
+	if !utils.IfMatch(r, article.Version) {
+		utils.WritePreconditionFailed(w)
+		return
+	}
+
+	var paragraph store.Paragraph
+	if err := json.NewDecoder(r.Body).Decode(&paragraph); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if strings.TrimSpace(paragraph.Headline) == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "headline is required"})
+		return
+	}
+
+	err := ah.articleStore.CreateParagraph(article, &paragraph, middleware.GetUser(r).ID)
+	if err != nil {
+		ah.writeParagraphError(w, r, err)
+		return
+	}
+
+	utils.SetETag(w, article.Version)
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"paragraph": paragraph})
+}
+
+func (ah *ArticleHandler) HandleUpdateParagraph(w http.ResponseWriter, r *http.Request) {
+	article := ah.loadEditableArticle(w, r)
+	if article == nil {
+		return
+	}
+
+	if !utils.IfMatch(r, article.Version) {
+		utils.WritePreconditionFailed(w)
+		return
+	}
+
+	paragraphID, err := utils.ReadInt64Param(r, "pid")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid paragraph id"})
+		return
+	}
+
+	paragraph := findParagraph(article, int(paragraphID))
+	if paragraph == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "paragraph not found"})
+		return
+	}
+
+	var req struct {
+		Headline *string `json:"headline"`
+		Body     *string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if req.Headline != nil {
+		if strings.TrimSpace(*req.Headline) == "" {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "headline cannot be empty"})
+			return
+		}
+		paragraph.Headline = *req.Headline
+	}
+
+	if req.Body != nil {
+		paragraph.Body = *req.Body
+	}
+
+	err = ah.articleStore.UpdateParagraph(article, paragraph, middleware.GetUser(r).ID)
+	if err != nil {
+		ah.writeParagraphError(w, r, err)
+		return
+	}
+
+	utils.SetETag(w, article.Version)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"paragraph": paragraph})
+}
+
+func (ah *ArticleHandler) HandleDeleteParagraph(w http.ResponseWriter, r *http.Request) {
+	article := ah.loadEditableArticle(w, r)
+	if article == nil {
+		return
+	}
+
+	if !utils.IfMatch(r, article.Version) {
+		utils.WritePreconditionFailed(w)
+		return
+	}
+
+	paragraphID, err := utils.ReadInt64Param(r, "pid")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid paragraph id"})
+		return
+	}
+
+	err = ah.articleStore.DeleteParagraph(article, int(paragraphID), middleware.GetUser(r).ID)
+	if err != nil {
+		ah.writeParagraphError(w, r, err)
+		return
+	}
+
+	utils.SetETag(w, article.Version)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "paragraph deleted successfully"})
+}
+
+func (ah *ArticleHandler) HandleReorderParagraphs(w http.ResponseWriter, r *http.Request) {
+	article := ah.loadEditableArticle(w, r)
+	if article == nil {
+		return
+	}
+
+	if !utils.IfMatch(r, article.Version) {
+		utils.WritePreconditionFailed(w)
+		return
+	}
+
+	var req struct {
+		ParagraphIDs []int `json:"paragraph_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	err := ah.articleStore.ReorderParagraphs(article, req.ParagraphIDs, middleware.GetUser(r).ID)
+	if err != nil {
+		ah.writeParagraphError(w, r, err)
+		return
+	}
+
+	reordered, err := ah.articleStore.GetArticleById(int64(article.ID))
+	if err != nil || reordered == nil {
+		ah.logger.Printf("ERROR: getArticleByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.SetETag(w, reordered.Version)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"article": reordered})
+}
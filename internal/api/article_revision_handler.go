@@ -0,0 +1,208 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/htojiddinov77-png/Articles/internal/middleware"
+	"github.com/htojiddinov77-png/Articles/internal/store"
+	"github.com/htojiddinov77-png/Articles/internal/utils"
+)
+
+// loadEditableArticle reads the article from the URL and makes sure the
+// caller may edit it. It writes the error response itself and returns nil
+// when the request should stop.
+func (ah *ArticleHandler) loadEditableArticle(w http.ResponseWriter, r *http.Request) *store.Article {
+	articleID, err := utils.ReadIDParam(r)
+	if err != nil {
+		ah.logger.Printf("ERROR: readIdParam: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid article id"})
+		return nil
+	}
+
+	article, err := ah.articleStore.GetArticleById(articleID)
+	if err != nil {
+		ah.logger.Printf("ERROR: getArticleByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+
+	if article == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "article not found"})
+		return nil
+	}
+
+	if !canModifyArticle(middleware.GetUser(r), article) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not allowed to access this article"})
+		return nil
+	}
+
+	return article
+}
+
+func (ah *ArticleHandler) HandleListRevisions(w http.ResponseWriter, r *http.Request) {
+	article := ah.loadEditableArticle(w, r)
+	if article == nil {
+		return
+	}
+
+	revisions, err := ah.articleStore.ListRevisions(int64(article.ID))
+	if err != nil {
+		ah.logger.Printf("ERROR: listRevisions: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"revisions": revisions})
+}
+
+func (ah *ArticleHandler) HandleGetRevision(w http.ResponseWriter, r *http.Request) {
+	article := ah.loadEditableArticle(w, r)
+	if article == nil {
+		return
+	}
+
+	revisionNumber, err := utils.ReadInt64Param(r, "rev")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid revision number"})
+		return
+	}
+
+	revision, err := ah.articleStore.GetRevision(int64(article.ID), int(revisionNumber))
+	if err != nil {
+		ah.logger.Printf("ERROR: getRevision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if revision == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "revision not found"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"revision": revision})
+}
+
+// HandleDiffRevisions compares two revisions given as ?from=&to=. Either
+// side may be "current" to compare against the live article.
+func (ah *ArticleHandler) HandleDiffRevisions(w http.ResponseWriter, r *http.Request) {
+	article := ah.loadEditableArticle(w, r)
+	if article == nil {
+		return
+	}
+
+	qs := r.URL.Query()
+	from, ok := ah.readRevisionSide(w, article, qs.Get("from"))
+	if !ok {
+		return
+	}
+	to, ok := ah.readRevisionSide(w, article, utils.ReadStringQuery(qs, "to", "current"))
+	if !ok {
+		return
+	}
+
+	fields := utils.Envelope{}
+	if from.Title != to.Title {
+		fields["title"] = utils.Envelope{"from": from.Title, "to": to.Title}
+	}
+	if from.Description != to.Description {
+		fields["description"] = utils.Envelope{"from": from.Description, "to": to.Description}
+	}
+	if from.Image != to.Image {
+		fields["image"] = utils.Envelope{"from": from.Image, "to": to.Image}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"from":       from.Revision,
+		"to":         to.Revision,
+		"fields":     fields,
+		"paragraphs": store.DiffParagraphs(from.Paragraphs, to.Paragraphs),
+	})
+}
+
+// readRevisionSide resolves one side of a diff. The live article is
+// reported as revision 0.
+func (ah *ArticleHandler) readRevisionSide(w http.ResponseWriter, article *store.Article, value string) (*store.ArticleRevision, bool) {
+	if value == "current" {
+		return &store.ArticleRevision{
+			ArticleID:   article.ID,
+			Title:       article.Title,
+			Description: article.Description,
+			Image:       article.Image,
+			Paragraphs:  article.Paragraphs,
+			CreatedAt:   article.UpdatedAt,
+		}, true
+	}
+
+	revisionNumber, err := strconv.Atoi(value)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "from and to must be revision numbers or \"current\""})
+		return nil, false
+	}
+
+	revision, err := ah.articleStore.GetRevision(int64(article.ID), revisionNumber)
+	if err != nil {
+		ah.logger.Printf("ERROR: getRevision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, false
+	}
+
+	if revision == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "revision not found"})
+		return nil, false
+	}
+
+	return revision, true
+}
+
+func (ah *ArticleHandler) HandleRestoreRevision(w http.ResponseWriter, r *http.Request) {
+	article := ah.loadEditableArticle(w, r)
+	if article == nil {
+		return
+	}
+
+	revisionNumber, err := utils.ReadInt64Param(r, "rev")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid revision number"})
+		return
+	}
+
+	revision, err := ah.articleStore.GetRevision(int64(article.ID), int(revisionNumber))
+	if err != nil {
+		ah.logger.Printf("ERROR: getRevision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if revision == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "revision not found"})
+		return
+	}
+
+	if !utils.IfMatch(r, article.Version) {
+		utils.WritePreconditionFailed(w)
+		return
+	}
+
+	article.Title = revision.Title
+	article.Description = revision.Description
+	article.Image = revision.Image
+	article.Paragraphs = revision.Paragraphs
+
+	// restoring is an edit like any other, so the current content becomes a
+	// revision of its own and the restore can itself be undone
+	err = ah.articleStore.UpdateArticle(article, middleware.GetUser(r).ID)
+	if errors.Is(err, store.ErrEditConflict) {
+		utils.WriteEditConflict(w, r)
+		return
+	}
+	if err != nil {
+		ah.logger.Printf("ERROR: restoreRevision: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.SetETag(w, article.Version)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"article": article})
+}
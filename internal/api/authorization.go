@@ -0,0 +1,48 @@
+package api
+
+import (
+	"github.com/htojiddinov77-png/Articles/internal/store"
+)
+
+// canModifyArticle reports whether user may edit or delete the article:
+// its author, or staff allowed to manage every article.
+func canModifyArticle(user *store.User, article *store.Article) bool {
+	if user == nil || user.IsAnonymous() {
+		return false
+	}
+	return article.AuthorId == user.ID || user.Can(store.PermissionArticlesManage)
+}
+
+// canViewArticle reports whether user may read the article. Published
+// articles are public unless moderation hid them, everything else is
+// limited to those who can edit it.
+func canViewArticle(user *store.User, article *store.Article) bool {
+	return article.IsPublic() || canModifyArticle(user, article)
+}
+
+// canModifyReview reports whether user may edit or delete the review: the
+// person who wrote it, or staff allowed to manage every review.
+func canModifyReview(user *store.User, review *store.Review) bool {
+	if user == nil || user.IsAnonymous() {
+		return false
+	}
+	return review.UserId == user.ID || user.Can(store.PermissionReviewsManage)
+}
+
+// canModifyComment reports whether user may delete the comment: the person
+// who wrote it, or staff allowed to manage every comment.
+func canModifyComment(user *store.User, comment *store.Comment) bool {
+	if user == nil || user.IsAnonymous() {
+		return false
+	}
+	return (comment.UserID != nil && *comment.UserID == user.ID) || user.Can(store.PermissionCommentsManage)
+}
+
+// canModifyUser reports whether user may edit or delete the account with
+// the given id.
+func canModifyUser(user *store.User, userID int64) bool {
+	if user == nil || user.IsAnonymous() {
+		return false
+	}
+	return int64(user.ID) == userID || user.Can(store.PermissionUsersManage)
+}
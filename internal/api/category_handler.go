@@ -0,0 +1,195 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/htojiddinov77-png/Articles/internal/store"
+	"github.com/htojiddinov77-png/Articles/internal/utils"
+)
+
+const maxCategoryNameLength = 100
+
+type CategoryHandler struct {
+	categoryStore store.CategoryStore
+	logger        *log.Logger
+}
+
+func NewCategoryHandler(categoryStore store.CategoryStore, logger *log.Logger) *CategoryHandler {
+	return &CategoryHandler{
+		categoryStore: categoryStore,
+		logger:        logger,
+	}
+}
+
+func (ch *CategoryHandler) HandleListCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := ch.categoryStore.ListCategories()
+	if err != nil {
+		ch.logger.Printf("ERROR: listCategories: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"categories": categories})
+}
+
+func (ch *CategoryHandler) HandleGetCategoryById(w http.ResponseWriter, r *http.Request) {
+	category := ch.loadCategory(w, r)
+	if category == nil {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"category": category})
+}
+
+func (ch *CategoryHandler) HandleCreateCategory(w http.ResponseWriter, r *http.Request) {
+	var category store.Category
+	err := json.NewDecoder(r.Body).Decode(&category)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if !ch.validateCategory(w, &category) {
+		return
+	}
+
+	createdCategory, err := ch.categoryStore.CreateCategory(&category)
+	if errors.Is(err, store.ErrDuplicateCategory) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ch.logger.Printf("ERROR: createCategory: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create category"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"category": createdCategory})
+}
+
+func (ch *CategoryHandler) HandleUpdateCategory(w http.ResponseWriter, r *http.Request) {
+	category := ch.loadCategory(w, r)
+	if category == nil {
+		return
+	}
+
+	// parent_id 0 moves the category to the top level
+	var req struct {
+		Name     *string `json:"name"`
+		ParentID *int    `json:"parent_id"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if req.Name != nil {
+		category.Name = *req.Name
+	}
+	if req.ParentID != nil {
+		category.ParentID = req.ParentID
+		if *req.ParentID == 0 {
+			category.ParentID = nil
+		}
+	}
+
+	if !ch.validateCategory(w, category) {
+		return
+	}
+
+	err = ch.categoryStore.UpdateCategory(category)
+	if errors.Is(err, store.ErrDuplicateCategory) || errors.Is(err, store.ErrCategoryCycle) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ch.logger.Printf("ERROR: updateCategory: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"category": category})
+}
+
+func (ch *CategoryHandler) HandleDeleteCategory(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid category id"})
+		return
+	}
+
+	err = ch.categoryStore.DeleteCategory(categoryID)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "category not found"})
+		return
+	}
+	if errors.Is(err, store.ErrCategoryNotEmpty) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ch.logger.Printf("ERROR: deleteCategory: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to delete category"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "category deleted successfully"})
+}
+
+// loadCategory fetches the category in the URL, writing the error response
+// and returning nil when there is none.
+func (ch *CategoryHandler) loadCategory(w http.ResponseWriter, r *http.Request) *store.Category {
+	categoryID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid category id"})
+		return nil
+	}
+
+	category, err := ch.categoryStore.GetCategoryById(categoryID)
+	if err != nil {
+		ch.logger.Printf("ERROR: getCategoryById: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+
+	if category == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "category not found"})
+		return nil
+	}
+
+	return category
+}
+
+// validateCategory trims the name, checks it and makes sure the parent
+// exists, writing a 400 and returning false otherwise.
+func (ch *CategoryHandler) validateCategory(w http.ResponseWriter, category *store.Category) bool {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" || len(category.Name) > maxCategoryNameLength {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "name must be between 1 and 100 characters"})
+		return false
+	}
+
+	if category.ParentID == nil {
+		return true
+	}
+
+	parent, err := ch.categoryStore.GetCategoryById(int64(*category.ParentID))
+	if err != nil {
+		ch.logger.Printf("ERROR: getCategoryById: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+
+	if parent == nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "parent category not found"})
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/htojiddinov77-png/Articles/internal/middleware"
+	"github.com/htojiddinov77-png/Articles/internal/store"
+	"github.com/htojiddinov77-png/Articles/internal/utils"
+)
+
+const maxCommentLength = 10000
+
+type CommentHandler struct {
+	commentStore store.CommentStore
+	articleStore store.ArticleStore
+	logger       *log.Logger
+}
+
+func NewCommentHandler(commentStore store.CommentStore, articleStore store.ArticleStore, logger *log.Logger) *CommentHandler {
+	return &CommentHandler{
+		commentStore: commentStore,
+		articleStore: articleStore,
+		logger:       logger,
+	}
+}
+
+func (ch *CommentHandler) HandleListArticleComments(w http.ResponseWriter, r *http.Request) {
+	article := ch.loadArticle(w, r)
+	if article == nil {
+		return
+	}
+
+	comments, err := ch.commentStore.ListArticleComments(int64(article.ID))
+	if err != nil {
+		ch.logger.Printf("ERROR: listArticleComments: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"comments": comments})
+}
+
+func (ch *CommentHandler) HandleCreateComment(w http.ResponseWriter, r *http.Request) {
+	article := ch.loadArticle(w, r)
+	if article == nil {
+		return
+	}
+
+	if !article.IsPublic() {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "only published articles can be commented on"})
+		return
+	}
+
+	var req struct {
+		Body     string `json:"body"`
+		ParentID *int   `json:"parent_id"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	body, err := validateCommentBody(req.Body)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	if req.ParentID != nil {
+		parent, err := ch.commentStore.GetCommentById(int64(*req.ParentID))
+		if err != nil {
+			ch.logger.Printf("ERROR: getCommentById: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+			return
+		}
+
+		if parent == nil || parent.ArticleID != article.ID || parent.Deleted {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "parent comment not found"})
+			return
+		}
+	}
+
+	userID := middleware.GetUser(r).ID
+	comment := &store.Comment{
+		ArticleID: article.ID,
+		ParentID:  req.ParentID,
+		UserID:    &userID,
+		Body:      body,
+		Replies:   []*store.Comment{},
+	}
+
+	createdComment, err := ch.commentStore.CreateComment(comment)
+	if err != nil {
+		ch.logger.Printf("ERROR: createComment: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create comment"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"comment": createdComment})
+}
+
+func (ch *CommentHandler) HandleUpdateComment(w http.ResponseWriter, r *http.Request) {
+	comment := ch.loadComment(w, r)
+	if comment == nil {
+		return
+	}
+
+	// only the author edits, and only for a short while after posting
+	user := middleware.GetUser(r)
+	if comment.UserID == nil || *comment.UserID != user.ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not allowed to update this comment"})
+		return
+	}
+
+	if !comment.CanEdit(time.Now()) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": fmt.Sprintf("comments can only be edited within %s of posting", store.CommentEditWindow)})
+		return
+	}
+
+	var req struct {
+		Body string `json:"body"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	comment.Body, err = validateCommentBody(req.Body)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = ch.commentStore.UpdateComment(comment)
+	if errors.Is(err, store.ErrCommentNotEditable) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": fmt.Sprintf("comments can only be edited within %s of posting", store.CommentEditWindow)})
+		return
+	}
+	if err != nil {
+		ch.logger.Printf("ERROR: updateComment: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"comment": comment})
+}
+
+func (ch *CommentHandler) HandleDeleteComment(w http.ResponseWriter, r *http.Request) {
+	comment := ch.loadComment(w, r)
+	if comment == nil {
+		return
+	}
+
+	if !canModifyComment(middleware.GetUser(r), comment) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not allowed to delete this comment"})
+		return
+	}
+
+	err := ch.commentStore.DeleteComment(int64(comment.ID))
+	if err != nil {
+		ch.logger.Printf("ERROR: deleteComment: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to delete comment"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "comment deleted successfully"})
+}
+
+// loadArticle fetches the article in the URL if the caller may see it,
+// writing the error response and returning nil otherwise.
+func (ch *CommentHandler) loadArticle(w http.ResponseWriter, r *http.Request) *store.Article {
+	articleID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid article id"})
+		return nil
+	}
+
+	article, err := ch.articleStore.GetArticleById(articleID)
+	if err != nil {
+		ch.logger.Printf("ERROR: getArticleById: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+
+	if article == nil || !canViewArticle(middleware.GetUser(r), article) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "article not found"})
+		return nil
+	}
+
+	return article
+}
+
+// loadComment fetches the comment in the URL, treating tombstones as gone.
+func (ch *CommentHandler) loadComment(w http.ResponseWriter, r *http.Request) *store.Comment {
+	commentID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid comment id"})
+		return nil
+	}
+
+	comment, err := ch.commentStore.GetCommentById(commentID)
+	if err != nil {
+		ch.logger.Printf("ERROR: getCommentById: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+
+	if comment == nil || comment.Deleted {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "comment not found"})
+		return nil
+	}
+
+	return comment
+}
+
+func validateCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", errors.New("body is required")
+	}
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		return "", fmt.Errorf("body must not be longer than %d characters", maxCommentLength)
+	}
+	return body, nil
+}
@@ -0,0 +1,13 @@
+package api
+
+import (
+	"embed"
+	"html/template"
+)
+
+//go:embed templates/*.html
+var pagesFS embed.FS
+
+// pages are the few HTML pages the API serves itself, for links that are
+// opened from emails in a browser.
+var pages = template.Must(template.ParseFS(pagesFS, "templates/*.html"))
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/htojiddinov77-png/Articles/internal/middleware"
+	"github.com/htojiddinov77-png/Articles/internal/store"
+	"github.com/htojiddinov77-png/Articles/internal/utils"
+)
+
+const maxReportReasonLength = 1000
+
+type ReportHandler struct {
+	reportStore  store.ReportStore
+	articleStore store.ArticleStore
+	reviewStore  store.ReviewStore
+	userStore    store.UserStore
+	logger       *log.Logger
+}
+
+func NewReportHandler(reportStore store.ReportStore, articleStore store.ArticleStore, reviewStore store.ReviewStore, userStore store.UserStore, logger *log.Logger) *ReportHandler {
+	return &ReportHandler{
+		reportStore:  reportStore,
+		articleStore: articleStore,
+		reviewStore:  reviewStore,
+		userStore:    userStore,
+		logger:       logger,
+	}
+}
+
+func (rh *ReportHandler) HandleCreateReport(w http.ResponseWriter, r *http.Request) {
+	var report store.Report
+	err := json.NewDecoder(r.Body).Decode(&report)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	user := middleware.GetUser(r)
+	report.ReporterID = user.ID
+
+	if !store.IsValidReportTarget(report.TargetType) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "target_type must be article, review or user"})
+		return
+	}
+
+	report.Reason = strings.TrimSpace(report.Reason)
+	if report.Reason == "" || utf8.RuneCountInString(report.Reason) > maxReportReasonLength {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "reason must be between 1 and 1000 characters"})
+		return
+	}
+
+	if report.TargetType == store.ReportTargetUser && report.TargetID == user.ID {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "you cannot report yourself"})
+		return
+	}
+
+	found, err := rh.targetExists(r, report.TargetType, int64(report.TargetID))
+	if err != nil {
+		rh.logger.Printf("ERROR: reportTargetExists: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if !found {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": report.TargetType + " not found"})
+		return
+	}
+
+	createdReport, err := rh.reportStore.CreateReport(&report)
+	if errors.Is(err, store.ErrDuplicateReport) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		rh.logger.Printf("ERROR: createReport: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create report"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"report": createdReport})
+}
+
+// targetExists reports whether the thing being reported exists and is
+// visible to the reporter.
+func (rh *ReportHandler) targetExists(r *http.Request, targetType string, id int64) (bool, error) {
+	switch targetType {
+	case store.ReportTargetArticle:
+		article, err := rh.articleStore.GetArticleById(id)
+		return article != nil && canViewArticle(middleware.GetUser(r), article), err
+	case store.ReportTargetReview:
+		review, err := rh.reviewStore.GetReviewById(id)
+		if err != nil || review == nil {
+			return false, err
+		}
+		article, err := rh.articleStore.GetArticleById(int64(review.ArticleId))
+		return article != nil && canViewArticle(middleware.GetUser(r), article), err
+	default:
+		user, err := rh.userStore.GetUserById(id)
+		return user != nil, err
+	}
+}
+
+func (rh *ReportHandler) HandleModerationQueue(w http.ResponseWriter, r *http.Request) {
+	groups, err := rh.reportStore.ListOpenReportGroups()
+	if err != nil {
+		rh.logger.Printf("ERROR: listOpenReportGroups: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"queue": groups})
+}
+
+func (rh *ReportHandler) HandleResolveReport(w http.ResponseWriter, r *http.Request) {
+	reportID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid report id"})
+		return
+	}
+
+	var req struct {
+		Action string `json:"action"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	report, err := rh.reportStore.GetReportById(reportID)
+	if err != nil {
+		rh.logger.Printf("ERROR: getReportById: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if report == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "report not found"})
+		return
+	}
+
+	if report.Status != store.ReportStatusOpen {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "report is already resolved"})
+		return
+	}
+
+	if err := store.ValidateReportAction(report.TargetType, req.Action); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	resolved, err := rh.reportStore.ResolveReports(report, req.Action, middleware.GetUser(r).ID)
+	if errors.Is(err, store.ErrProtectedUser) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, store.ErrReportResolved) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		rh.logger.Printf("ERROR: resolveReports: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"target_type":      report.TargetType,
+		"target_id":        report.TargetID,
+		"action":           req.Action,
+		"resolved_reports": resolved,
+	})
+}
+
+// HandleUnhideArticle reverses hide_content on an article.
+func (rh *ReportHandler) HandleUnhideArticle(w http.ResponseWriter, r *http.Request) {
+	rh.unhide(w, r, store.ReportTargetArticle)
+}
+
+// HandleUnhideReview reverses hide_content on a review.
+func (rh *ReportHandler) HandleUnhideReview(w http.ResponseWriter, r *http.Request) {
+	rh.unhide(w, r, store.ReportTargetReview)
+}
+
+func (rh *ReportHandler) unhide(w http.ResponseWriter, r *http.Request, targetType string) {
+	id, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid " + targetType + " id"})
+		return
+	}
+
+	err = rh.reportStore.UnhideContent(targetType, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": targetType + " not found or not hidden"})
+		return
+	}
+	if err != nil {
+		rh.logger.Printf("ERROR: unhideContent: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"target_type": targetType, "target_id": id, "hidden": false})
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/htojiddinov77-png/Articles/internal/middleware"
 	"github.com/htojiddinov77-png/Articles/internal/store"
 	"github.com/htojiddinov77-png/Articles/internal/utils"
 )
@@ -34,6 +35,10 @@ func (rh *ReviewHandler) HandleCreateReview(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// reviews are always written by the caller, whatever the body says
+	user := middleware.GetUser(r)
+	review.UserId = user.ID
+
 	if review.Rating < 1 || review.Rating > 5 {
 		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "rating must be between 1 and 5"})
 		return
@@ -44,12 +49,21 @@ func (rh *ReviewHandler) HandleCreateReview(w http.ResponseWriter, r *http.Reque
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to verify article"})
 		return
 	}
-	if existingArticle == nil {
+	if existingArticle == nil || !existingArticle.IsPublic() {
 		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "article not found"})
 		return
 	}
 
+	if existingArticle.AuthorId == user.ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you cannot review your own article"})
+		return
+	}
+
 	createdReview, err := rh.reviewStore.CreateReview(&review)
+	if errors.Is(err, store.ErrDuplicateReview) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		rh.logger.Printf("ERROR: createReview: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create review"})
@@ -59,6 +73,65 @@ func (rh *ReviewHandler) HandleCreateReview(w http.ResponseWriter, r *http.Reque
 	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"review": createdReview})
 }
 
+func (rh *ReviewHandler) HandleListArticleReviews(w http.ResponseWriter, r *http.Request) {
+	articleID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid article id"})
+		return
+	}
+
+	filter, err := readReviewFilter(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	article, err := rh.articleStore.GetArticleById(articleID)
+	if err != nil {
+		rh.logger.Printf("ERROR: getArticleById: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if article == nil || !canViewArticle(middleware.GetUser(r), article) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "article not found"})
+		return
+	}
+
+	reviews, metadata, err := rh.reviewStore.ListArticleReviews(articleID, filter)
+	if err != nil {
+		rh.logger.Printf("ERROR: listArticleReviews: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	summary, err := rh.reviewStore.GetRatingSummary(articleID)
+	if err != nil {
+		rh.logger.Printf("ERROR: getRatingSummary: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"reviews": reviews, "summary": summary, "metadata": metadata})
+}
+
+func readReviewFilter(r *http.Request) (store.ReviewFilter, error) {
+	qs := r.URL.Query()
+	filter := store.ReviewFilter{
+		Sort: utils.ReadStringQuery(qs, "sort", "newest"),
+	}
+
+	var err error
+	if filter.Page, err = utils.ReadIntQuery(qs, "page", 1); err != nil {
+		return filter, err
+	}
+	if filter.PageSize, err = utils.ReadIntQuery(qs, "page_size", store.DefaultPageSize); err != nil {
+		return filter, err
+	}
+
+	return filter, filter.Validate()
+}
+
 func (rh *ReviewHandler) HandleGetReviewByid(w http.ResponseWriter, r *http.Request) {
 	reviewId, err := utils.ReadIDParam(r)
 	if err != nil {
@@ -78,9 +151,33 @@ func (rh *ReviewHandler) HandleGetReviewByid(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	if !rh.checkReviewArticleVisible(w, r, review) {
+		return
+	}
+
+	utils.SetETag(w, review.Version)
 	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"review": review})
 }
 
+// checkReviewArticleVisible makes sure the caller can see the article the
+// review belongs to, so reviews of drafts and hidden articles stay private.
+// It writes a 404 and returns false when they can't.
+func (rh *ReviewHandler) checkReviewArticleVisible(w http.ResponseWriter, r *http.Request, review *store.Review) bool {
+	article, err := rh.articleStore.GetArticleById(int64(review.ArticleId))
+	if err != nil {
+		rh.logger.Printf("ERROR: getArticleById: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+
+	if article == nil || !canViewArticle(middleware.GetUser(r), article) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "review not found"})
+		return false
+	}
+
+	return true
+}
+
 func (rh *ReviewHandler) HandleUpdateReviewById(w http.ResponseWriter, r *http.Request) {
 	reviewId, err := utils.ReadIDParam(r)
 	if err != nil{
@@ -101,6 +198,16 @@ func (rh *ReviewHandler) HandleUpdateReviewById(w http.ResponseWriter, r *http.R
 		return
 	}
 
+	if !canModifyReview(middleware.GetUser(r), existingReview) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not allowed to update this review"})
+		return
+	}
+
+	if !utils.IfMatch(r, existingReview.Version) {
+		utils.WritePreconditionFailed(w)
+		return
+	}
+
 
 	type UpdatedReviewRequest struct {
 		ReviewText *string `json:"review_text"`
@@ -137,12 +244,17 @@ func (rh *ReviewHandler) HandleUpdateReviewById(w http.ResponseWriter, r *http.R
 	
 
 	err = rh.reviewStore.UpdateReview(existingReview)
+	if errors.Is(err, store.ErrEditConflict) {
+		utils.WriteEditConflict(w, r)
+		return
+	}
 	if err != nil {
 		rh.logger.Printf("Error updating review: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "Internal server error"})
 		return
 	}
 
+	utils.SetETag(w, existingReview.Version)
 	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"review": existingReview})
 }
 
@@ -154,10 +266,32 @@ func (rh *ReviewHandler) HandleDeleteReview(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = rh.reviewStore.DeleteReview(reviewID)
+	existingReview, err := rh.reviewStore.GetReviewById(reviewID)
 	if err != nil {
-		if errors.Is(err, store.ErrReviewNotfound) {
-			utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "Review not found"})
+		rh.logger.Printf("Error getting review by ID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if existingReview == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "Review not found"})
+		return
+	}
+
+	if !canModifyReview(middleware.GetUser(r), existingReview) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not allowed to delete this review"})
+		return
+	}
+
+	if !utils.IfMatch(r, existingReview.Version) {
+		utils.WritePreconditionFailed(w)
+		return
+	}
+
+	err = rh.reviewStore.DeleteReview(reviewID, existingReview.Version)
+	if err != nil {
+		if errors.Is(err, store.ErrEditConflict) {
+			utils.WriteEditConflict(w, r)
 			return
 		}
 
@@ -168,3 +302,47 @@ func (rh *ReviewHandler) HandleDeleteReview(w http.ResponseWriter, r *http.Reque
 
 	utils.WriteJSON(w, http.StatusNoContent, nil)
 }
+
+func (rh *ReviewHandler) HandleRestoreReview(w http.ResponseWriter, r *http.Request) {
+	reviewID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid review id"})
+		return
+	}
+
+	deletedReview, err := rh.reviewStore.GetDeletedReviewById(reviewID)
+	if err != nil {
+		rh.logger.Printf("ERROR: getDeletedReviewById: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if deletedReview == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "review not found in trash"})
+		return
+	}
+
+	if !canModifyReview(middleware.GetUser(r), deletedReview) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not allowed to restore this review"})
+		return
+	}
+
+	err = rh.reviewStore.RestoreReview(reviewID)
+	if errors.Is(err, store.ErrReviewNotfound) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "review not found in trash"})
+		return
+	}
+	if errors.Is(err, store.ErrDuplicateReview) {
+		// the author wrote a new review on the article since deleting this one
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		rh.logger.Printf("ERROR: restoreReview: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.SetETag(w, deletedReview.Version)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"review": deletedReview})
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/htojiddinov77-png/Articles/internal/middleware"
+	"github.com/htojiddinov77-png/Articles/internal/store"
+	"github.com/htojiddinov77-png/Articles/internal/utils"
+)
+
+func (rh *ReviewHandler) HandleCreateReviewResponse(w http.ResponseWriter, r *http.Request) {
+	review := rh.loadRespondableReview(w, r)
+	if review == nil {
+		return
+	}
+
+	response := &store.ReviewResponse{
+		ReviewID: review.ID,
+		UserID:   middleware.GetUser(r).ID,
+	}
+	if !readResponseBody(w, r, response) {
+		return
+	}
+
+	err := rh.reviewStore.CreateReviewResponse(response)
+	if errors.Is(err, store.ErrDuplicateReviewResponse) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		rh.logger.Printf("ERROR: createReviewResponse: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create response"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"response": response})
+}
+
+func (rh *ReviewHandler) HandleUpdateReviewResponse(w http.ResponseWriter, r *http.Request) {
+	review := rh.loadRespondableReview(w, r)
+	if review == nil {
+		return
+	}
+
+	response := &store.ReviewResponse{ReviewID: review.ID}
+	if !readResponseBody(w, r, response) {
+		return
+	}
+
+	err := rh.reviewStore.UpdateReviewResponse(response)
+	if errors.Is(err, store.ErrReviewResponseNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "response not found"})
+		return
+	}
+	if err != nil {
+		rh.logger.Printf("ERROR: updateReviewResponse: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"response": response})
+}
+
+func (rh *ReviewHandler) HandleDeleteReviewResponse(w http.ResponseWriter, r *http.Request) {
+	review := rh.loadRespondableReview(w, r)
+	if review == nil {
+		return
+	}
+
+	err := rh.reviewStore.DeleteReviewResponse(int64(review.ID))
+	if errors.Is(err, store.ErrReviewResponseNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "response not found"})
+		return
+	}
+	if err != nil {
+		rh.logger.Printf("ERROR: deleteReviewResponse: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "response deleted successfully"})
+}
+
+// loadRespondableReview fetches the review in the URL and checks that the
+// caller wrote the article it reviews, writing the error response and
+// returning nil otherwise.
+func (rh *ReviewHandler) loadRespondableReview(w http.ResponseWriter, r *http.Request) *store.Review {
+	reviewID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid review id"})
+		return nil
+	}
+
+	review, err := rh.reviewStore.GetReviewById(reviewID)
+	if err != nil {
+		rh.logger.Printf("ERROR: getReviewById: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+
+	if review == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "review not found"})
+		return nil
+	}
+
+	article, err := rh.articleStore.GetArticleById(int64(review.ArticleId))
+	if err != nil {
+		rh.logger.Printf("ERROR: getArticleById: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+
+	if article == nil || article.AuthorId != middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "only the author of the article can respond to its reviews"})
+		return nil
+	}
+
+	return review
+}
+
+func readResponseBody(w http.ResponseWriter, r *http.Request, response *store.ReviewResponse) bool {
+	var req struct {
+		Body string `json:"body"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return false
+	}
+
+	response.Body = strings.TrimSpace(req.Body)
+	if response.Body == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "body is required"})
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/htojiddinov77-png/Articles/internal/middleware"
+	"github.com/htojiddinov77-png/Articles/internal/store"
+	"github.com/htojiddinov77-png/Articles/internal/utils"
+)
+
+func (rh *ReviewHandler) HandleVoteReview(w http.ResponseWriter, r *http.Request) {
+	review := rh.loadVotableReview(w, r)
+	if review == nil {
+		return
+	}
+
+	var req struct {
+		Helpful *bool `json:"helpful"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil || req.Helpful == nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "helpful must be true or false"})
+		return
+	}
+
+	err = rh.reviewStore.VoteReview(int64(review.ID), middleware.GetUser(r).ID, *req.Helpful)
+	if err != nil {
+		rh.logger.Printf("ERROR: voteReview: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	rh.writeReview(w, int64(review.ID))
+}
+
+func (rh *ReviewHandler) HandleDeleteReviewVote(w http.ResponseWriter, r *http.Request) {
+	review := rh.loadVotableReview(w, r)
+	if review == nil {
+		return
+	}
+
+	err := rh.reviewStore.DeleteReviewVote(int64(review.ID), middleware.GetUser(r).ID)
+	if errors.Is(err, store.ErrVoteNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "you have not voted on this review"})
+		return
+	}
+	if err != nil {
+		rh.logger.Printf("ERROR: deleteReviewVote: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	rh.writeReview(w, int64(review.ID))
+}
+
+// loadVotableReview fetches the review in the URL and makes sure the caller
+// can see its article and isn't its author, writing the error response and
+// returning nil otherwise.
+func (rh *ReviewHandler) loadVotableReview(w http.ResponseWriter, r *http.Request) *store.Review {
+	reviewID, err := utils.ReadIDParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid review id"})
+		return nil
+	}
+
+	review, err := rh.reviewStore.GetReviewById(reviewID)
+	if err != nil {
+		rh.logger.Printf("ERROR: getReviewById: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+
+	if review == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "review not found"})
+		return nil
+	}
+
+	if !rh.checkReviewArticleVisible(w, r, review) {
+		return nil
+	}
+
+	if review.UserId == middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you cannot vote on your own review"})
+		return nil
+	}
+
+	return review
+}
+
+// writeReview responds with the review as it is stored now, so vote counts
+// are up to date.
+func (rh *ReviewHandler) writeReview(w http.ResponseWriter, reviewID int64) {
+	review, err := rh.reviewStore.GetReviewById(reviewID)
+	if err != nil {
+		rh.logger.Printf("ERROR: getReviewById: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if review == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "review not found"})
+		return
+	}
+
+	utils.SetETag(w, review.Version)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"review": review})
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/htojiddinov77-png/Articles/internal/store"
+	"github.com/htojiddinov77-png/Articles/internal/utils"
+)
+
+type TagHandler struct {
+	tagStore store.TagStore
+	logger   *log.Logger
+}
+
+func NewTagHandler(tagStore store.TagStore, logger *log.Logger) *TagHandler {
+	return &TagHandler{
+		tagStore: tagStore,
+		logger:   logger,
+	}
+}
+
+func (th *TagHandler) HandleListTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := th.tagStore.ListTags()
+	if err != nil {
+		th.logger.Printf("ERROR: listTags: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"tags": tags})
+}
@@ -1,13 +1,16 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/htojiddinov77-png/Articles/internal/middleware"
 	"github.com/htojiddinov77-png/Articles/internal/store"
-	"github.com/htojiddinov77-png/Articles/internal/tokens"
 	"github.com/htojiddinov77-png/Articles/internal/utils"
 )
 
@@ -60,12 +63,100 @@ func (h *TokenHandler) HandleCreateToken(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	token, err := h.tokenStore.CreateNewToken(user.ID, 24*time.Hour, tokens.ScopeAuth)
+	if user.IsLockedOut(time.Now()) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": user.LockoutMessage()})
+		return
+	}
+
+	token, refreshToken, err := h.tokenStore.CreateSession(user.ID, r.UserAgent(), utils.ClientIP(r))
 	if err != nil {
 		h.logger.Printf("ERROR: Creating token %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"auth_token": token})
-}
\ No newline at end of file
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"auth_token": token, "refresh_token": refreshToken})
+}
+
+// HandleRefreshToken trades a refresh token for a new pair of tokens. The
+// old refresh token stops working.
+func (h *TokenHandler) HandleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if req.RefreshToken == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "refresh_token is required"})
+		return
+	}
+
+	token, refreshToken, err := h.tokenStore.RotateRefreshToken(req.RefreshToken, r.UserAgent(), utils.ClientIP(r))
+	if errors.Is(err, store.ErrRefreshTokenReused) {
+		h.logger.Printf("WARNING: refresh token reused from %s, its session was revoked", utils.ClientIP(r))
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "refresh token was already used, please log in again"})
+		return
+	}
+	if errors.Is(err, store.ErrInvalidRefreshToken) {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: rotating refresh token: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"auth_token": token, "refresh_token": refreshToken})
+}
+
+// HandleDeleteToken logs out by revoking the token the request was made
+// with and its refresh token. Other sessions stay signed in.
+func (h *TokenHandler) HandleDeleteToken(w http.ResponseWriter, r *http.Request) {
+	err := h.tokenStore.DeleteToken(middleware.GetTokenHash(r))
+	if err != nil {
+		h.logger.Printf("ERROR: deleting token: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusNoContent, nil)
+}
+
+func (h *TokenHandler) HandleListSessions(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+
+	sessions, err := h.tokenStore.ListSessions(user.ID, middleware.GetTokenHash(r))
+	if err != nil {
+		h.logger.Printf("ERROR: listing sessions: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"sessions": sessions})
+}
+
+func (h *TokenHandler) HandleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "id")
+	if sessionID == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid session id"})
+		return
+	}
+
+	user := middleware.GetUser(r)
+	err := h.tokenStore.DeleteSession(user.ID, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "session not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: deleting session: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusNoContent, nil)
+}
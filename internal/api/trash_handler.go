@@ -0,0 +1,42 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/htojiddinov77-png/Articles/internal/middleware"
+	"github.com/htojiddinov77-png/Articles/internal/store"
+	"github.com/htojiddinov77-png/Articles/internal/utils"
+)
+
+type TrashHandler struct {
+	trashStore store.TrashStore
+	retention  time.Duration
+	logger     *log.Logger
+}
+
+// NewTrashHandler takes the retention the purge job runs with, so the
+// listing can tell when each item will be gone.
+func NewTrashHandler(trashStore store.TrashStore, retention time.Duration, logger *log.Logger) *TrashHandler {
+	return &TrashHandler{
+		trashStore: trashStore,
+		retention:  retention,
+		logger:     logger,
+	}
+}
+
+func (th *TrashHandler) HandleListTrash(w http.ResponseWriter, r *http.Request) {
+	items, err := th.trashStore.ListTrash(middleware.GetUser(r).ID)
+	if err != nil {
+		th.logger.Printf("ERROR: listTrash: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	for _, item := range items {
+		item.PurgeAt = item.DeletedAt.Add(th.retention)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"trash": items})
+}
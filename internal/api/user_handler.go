@@ -2,15 +2,19 @@ package api
 
 import (
 	"crypto/sha256"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/htojiddinov77-png/Articles/internal/mailer"
+	"github.com/htojiddinov77-png/Articles/internal/middleware"
 	"github.com/htojiddinov77-png/Articles/internal/store"
 	"github.com/htojiddinov77-png/Articles/internal/tokens"
 	"github.com/htojiddinov77-png/Articles/internal/utils"
@@ -29,16 +33,32 @@ type registerUserRequest struct {
 	Bio      string `json:"bio"`
 }
 
+const (
+	// activationTokenTTL is how long a new account has to verify its email.
+	activationTokenTTL = 3 * 24 * time.Hour
+	// activationResendInterval is how long a user has to wait before
+	// another activation email is sent.
+	activationResendInterval = 5 * time.Minute
+	// passwordResetTokenTTL is how long a password reset link works.
+	passwordResetTokenTTL = 10 * time.Minute
+)
+
 type UserHandler struct {
 	userStore  store.UserStore
 	tokenStore store.TokenStore
-	logger     *log.Logger
+	mailer     mailer.Mailer
+	// baseURL is where the API is reachable from outside, used to build
+	// links in emails.
+	baseURL string
+	logger  *log.Logger
 }
 
-func NewUserHandler(userstore store.UserStore, tokenStore store.TokenStore, logger *log.Logger) *UserHandler {
+func NewUserHandler(userstore store.UserStore, tokenStore store.TokenStore, mailer mailer.Mailer, baseURL string, logger *log.Logger) *UserHandler {
 	return &UserHandler{
 		userStore:  userstore,
 		tokenStore: tokenStore,
+		mailer:     mailer,
+		baseURL:    strings.TrimRight(baseURL, "/"),
 		logger:     logger,
 	}
 }
@@ -111,23 +131,68 @@ func (uh *UserHandler) HandlePasswordResetRequest(w http.ResponseWriter, r *http
 		return
 	}
 
+	// the answer is the same whether or not the email has an account, so
+	// the endpoint can't be used to find out who is registered
+	response := utils.Envelope{"message": "if an account uses this email, a password reset link has been sent to it"}
 	if user == nil {
-		// need feedback
-		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "User not found"})
+		utils.WriteJSON(w, http.StatusAccepted, response)
 		return
 	}
 
-	token, err := uh.tokenStore.CreateNewToken(user.ID, 10*time.Minute, tokens.ScopePasswordReset)
+	token, err := uh.tokenStore.CreateNewToken(user.ID, passwordResetTokenTTL, tokens.ScopePasswordReset)
 	if err != nil {
 		uh.logger.Printf("Error creating  reset token: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "Internal server error"})
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
-		"message": "password reset token generated",
-		"token":   token,
+	uh.sendEmail(user.Email, "password_reset.tmpl", map[string]any{
+		"Username": user.Username,
+		"ResetURL": uh.baseURL + PasswordResetPath(token.Plaintext),
+		"Expiry":   token.Expiry.UTC().Format(time.RFC1123),
+	})
+
+	utils.WriteJSON(w, http.StatusAccepted, response)
+}
+
+// sendEmail renders and sends an email. Failures are only logged: the
+// action that triggered the email has already happened.
+func (uh *UserHandler) sendEmail(recipient, templateFile string, data any) {
+	msg, err := mailer.NewMessage(recipient, templateFile, data)
+	if err != nil {
+		uh.logger.Printf("ERROR: rendering %s: %v", templateFile, err)
+		return
+	}
+
+	if err := uh.mailer.Send(msg); err != nil {
+		uh.logger.Printf("ERROR: sending %s: %v", templateFile, err)
+	}
+}
+
+// PasswordResetPath is where the link in a password reset email points. A
+// GET shows HandlePasswordResetForm, which POSTs to HandlePasswordReset.
+func PasswordResetPath(token string) string {
+	return "/users/password-reset/" + url.PathEscape(token)
+}
+
+// HandlePasswordResetForm serves the page the reset link opens in a
+// browser. The token is only checked once the form is submitted.
+func (uh *UserHandler) HandlePasswordResetForm(w http.ResponseWriter, r *http.Request) {
+	plainTextToken := chi.URLParam(r, "token")
+	if plainTextToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	err := pages.ExecuteTemplate(w, "password_reset_form.html", map[string]any{
+		"Action": PasswordResetPath(plainTextToken),
 	})
+	if err != nil {
+		uh.logger.Printf("ERROR: rendering password reset form: %v", err)
+	}
 }
 
 func (uh *UserHandler) HandlePasswordReset(w http.ResponseWriter, r *http.Request) {
@@ -205,14 +270,23 @@ func (uh *UserHandler) HandlePasswordReset(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := uh.tokenStore.DeleteAllTokensForUser(user.ID, token.Scope); err != nil {
-		uh.logger.Printf("Error deleting token: %v", err)
-	}
+	// sessions opened with the old password end too
+	uh.revokeTokens(user.ID, token.Scope, tokens.ScopeAuth, tokens.ScopeRefresh)
 
 	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "password updated successfully"})
 
 }
 
+// revokeTokens deletes the user's tokens of the given scopes. Failures are
+// only logged: the password has already been changed.
+func (uh *UserHandler) revokeTokens(userID int, scopes ...string) {
+	for _, scope := range scopes {
+		if err := uh.tokenStore.DeleteAllTokensForUser(userID, scope); err != nil {
+			uh.logger.Printf("ERROR: deleting %s tokens: %v", scope, err)
+		}
+	}
+}
+
 func (uh *UserHandler) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
 	userId, err := utils.ReadIDParam(r)
 	if err != nil {
@@ -273,6 +347,8 @@ func (uh *UserHandler) HandleChangePassword(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	uh.revokeTokens(oldUserPassword.ID, tokens.ScopeAuth, tokens.ScopeRefresh)
+
 	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "password updated successfully"})
 }
 
@@ -331,9 +407,126 @@ func (uh *UserHandler) HandleRegisterUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	token, err := uh.tokenStore.CreateNewToken(user.ID, activationTokenTTL, tokens.ScopeActivation)
+	if err != nil {
+		uh.logger.Printf("ERROR: creating activation token %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	uh.sendEmail(user.Email, "user_activation.tmpl", map[string]any{
+		"Username": user.Username,
+		"Token":    token.Plaintext,
+		"Expiry":   token.Expiry.UTC().Format(time.RFC1123),
+	})
+
 	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"user": user})
 }
 
+// HandleResendActivation issues a new activation token and emails it, for
+// users whose first email got lost or whose token expired. The answer is
+// always the same, so it can't be used to find out who is registered, and
+// at most one email is sent per account every activationResendInterval.
+func (uh *UserHandler) HandleResendActivation(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if req.Email == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "email is required"})
+		return
+	}
+
+	response := utils.Envelope{"message": "if this email belongs to an account that is not activated yet, a new activation email has been sent"}
+
+	user, err := uh.userStore.GetUserByEmail(req.Email)
+	if err != nil {
+		uh.logger.Printf("ERROR: getting user by email: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if user == nil || user.Activated {
+		utils.WriteJSON(w, http.StatusAccepted, response)
+		return
+	}
+
+	last, err := uh.tokenStore.LastTokenCreatedAt(user.ID, tokens.ScopeActivation)
+	if err != nil {
+		uh.logger.Printf("ERROR: getting last activation token: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if last != nil && time.Since(*last) < activationResendInterval {
+		utils.WriteJSON(w, http.StatusAccepted, response)
+		return
+	}
+
+	// only the newest token works, earlier emails become useless
+	if err := uh.tokenStore.DeleteAllTokensForUser(user.ID, tokens.ScopeActivation); err != nil {
+		uh.logger.Printf("ERROR: deleting activation tokens: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	token, err := uh.tokenStore.CreateNewToken(user.ID, activationTokenTTL, tokens.ScopeActivation)
+	if err != nil {
+		uh.logger.Printf("ERROR: creating activation token %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	uh.sendEmail(user.Email, "user_activation.tmpl", map[string]any{
+		"Username": user.Username,
+		"Token":    token.Plaintext,
+		"Expiry":   token.Expiry.UTC().Format(time.RFC1123),
+	})
+
+	utils.WriteJSON(w, http.StatusAccepted, response)
+}
+
+func (uh *UserHandler) HandleActivateUser(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if req.Token == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "token is required"})
+		return
+	}
+
+	user, err := uh.userStore.GetUserToken(tokens.ScopeActivation, req.Token)
+	if err != nil {
+		uh.logger.Printf("ERROR: getting user by activation token: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if user == nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid or expired activation token"})
+		return
+	}
+
+	err = uh.userStore.ActivateUser(int64(user.ID))
+	if err != nil {
+		uh.logger.Printf("ERROR: activating user: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	user.Activated = true
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"user": user})
+}
+
 func (uh *UserHandler) HandleGetUserById(w http.ResponseWriter, r *http.Request) {
 	userID, err := utils.ReadIDParam(r)
 	if err != nil {
@@ -347,6 +540,13 @@ func (uh *UserHandler) HandleGetUserById(w http.ResponseWriter, r *http.Request)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "Internal server error"})
 		return
 	}
+
+	if user == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "User not found"})
+		return
+	}
+
+	utils.SetETag(w, user.Version)
 	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"user": user})
 }
 
@@ -358,6 +558,11 @@ func (uh *UserHandler) HandleUpdateUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !canModifyUser(middleware.GetUser(r), userID) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not allowed to update this user"})
+		return
+	}
+
 	existingUser, err := uh.userStore.GetUserById(userID)
 	if err != nil {
 		uh.logger.Printf("Error getting user by ID: %v", err)
@@ -370,6 +575,11 @@ func (uh *UserHandler) HandleUpdateUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !utils.IfMatch(r, existingUser.Version) {
+		utils.WritePreconditionFailed(w)
+		return
+	}
+
 	var updatedUserRequest struct {
 		Username *string `json:"username"`
 		Email    *string `json:"email"`
@@ -432,12 +642,17 @@ func (uh *UserHandler) HandleUpdateUser(w http.ResponseWriter, r *http.Request)
 	}
 
 	err = uh.userStore.UpdateUser(existingUser)
+	if errors.Is(err, store.ErrEditConflict) {
+		utils.WriteEditConflict(w, r)
+		return
+	}
 	if err != nil {
 		uh.logger.Printf("Error updating user: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "Internal server error"})
 		return
 	}
 
+	utils.SetETag(w, existingUser.Version)
 	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"user": existingUser})
 }
 
@@ -449,6 +664,25 @@ func (uh *UserHandler) HandleDeleteUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if !canModifyUser(middleware.GetUser(r), userID) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not allowed to delete this user"})
+		return
+	}
+
+	if r.Header.Get("If-Match") != "" {
+		existingUser, err := uh.userStore.GetUserById(userID)
+		if err != nil {
+			uh.logger.Printf("Error getting user by ID: %v", err)
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "Internal server error"})
+			return
+		}
+
+		if existingUser != nil && !utils.IfMatch(r, existingUser.Version) {
+			utils.WritePreconditionFailed(w)
+			return
+		}
+	}
+
 	err = uh.userStore.DeleteUser(userID)
 	if err != nil {
 		uh.logger.Printf("Error deleting user: %v", err)
@@ -458,3 +692,142 @@ func (uh *UserHandler) HandleDeleteUser(w http.ResponseWriter, r *http.Request)
 
 	utils.WriteJSON(w, http.StatusNoContent, nil)
 }
+
+func (uh *UserHandler) HandleUpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadIDParam(r)
+	if err != nil {
+		uh.logger.Printf("Error reading user ID: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "Invalid request payload"})
+		return
+	}
+
+	existingUser, err := uh.userStore.GetUserById(userID)
+	if err != nil {
+		uh.logger.Printf("Error getting user by ID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "Internal server error"})
+		return
+	}
+
+	if existingUser == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "User not found"})
+		return
+	}
+
+	err = uh.userStore.SetUserRole(userID, req.Role)
+	if err != nil {
+		if errors.Is(err, store.ErrRoleNotFound) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "unknown role"})
+			return
+		}
+		uh.logger.Printf("Error setting user role: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "Internal server error"})
+		return
+	}
+
+	existingUser.Role = req.Role
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"user": existingUser})
+}
+
+// HandleSuspendUser suspends or, with "ban" set, bans a user. A suspension
+// lasts until "until" or, without it, until it is lifted. All of the user's
+// tokens are revoked.
+func (uh *UserHandler) HandleSuspendUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadIDParam(r)
+	if err != nil {
+		uh.logger.Printf("Error reading user ID: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "Invalid user ID"})
+		return
+	}
+
+	var req struct {
+		Reason string     `json:"reason"`
+		Until  *time.Time `json:"until"`
+		Ban    bool       `json:"ban"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "Invalid request payload"})
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "reason is required"})
+		return
+	}
+
+	if req.Ban && req.Until != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "a ban cannot have an end date"})
+		return
+	}
+
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "until must be in the future"})
+		return
+	}
+
+	if int64(middleware.GetUser(r).ID) == userID {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "you cannot suspend yourself"})
+		return
+	}
+
+	suspension := store.Suspension{Status: store.UserStatusSuspended, Until: req.Until, Reason: req.Reason}
+	if req.Ban {
+		suspension.Status = store.UserStatusBanned
+	}
+
+	err = uh.userStore.SuspendUser(userID, suspension)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		uh.logger.Printf("Error suspending user: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "Internal server error"})
+		return
+	}
+
+	uh.respondWithUser(w, userID)
+}
+
+// HandleUnsuspendUser lifts a suspension or ban. Revoked tokens stay
+// revoked, the user has to sign in again.
+func (uh *UserHandler) HandleUnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadIDParam(r)
+	if err != nil {
+		uh.logger.Printf("Error reading user ID: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "Invalid user ID"})
+		return
+	}
+
+	err = uh.userStore.UnsuspendUser(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		uh.logger.Printf("Error unsuspending user: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "Internal server error"})
+		return
+	}
+
+	uh.respondWithUser(w, userID)
+}
+
+func (uh *UserHandler) respondWithUser(w http.ResponseWriter, userID int64) {
+	user, err := uh.userStore.GetUserById(userID)
+	if err != nil || user == nil {
+		uh.logger.Printf("Error getting user by ID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "Internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"user": user})
+}
@@ -1,29 +1,69 @@
 package app
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/htojiddinov77-png/Articles/internal/api"
+	"github.com/htojiddinov77-png/Articles/internal/mailer"
 	"github.com/htojiddinov77-png/Articles/internal/middleware"
 	"github.com/htojiddinov77-png/Articles/internal/migrations"
+	"github.com/htojiddinov77-png/Articles/internal/scheduler"
 	"github.com/htojiddinov77-png/Articles/internal/store"
 )
 
+const (
+	mailWorkers         = 2
+	mailQueueSize       = 100
+	mailShutdownTimeout = 10 * time.Second
+)
+
+type Config struct {
+	// PublishInterval is how often scheduled articles are checked for publishing.
+	PublishInterval time.Duration
+	// TrashRetention is how long deleted articles and reviews can be
+	// restored before they are purged for good.
+	TrashRetention time.Duration
+	// SMTP configures outgoing email. Without a host emails are written to
+	// MailFile, or to stdout when that is empty too.
+	SMTP     SMTPConfig
+	MailFile string
+	// BaseURL is the public address of the API, used in links in emails.
+	BaseURL string
+}
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Sender   string
+}
+
 type Application struct {
-	Logger         *log.Logger
-	ArticleHandler *api.ArticleHandler
-	UserHandler    *api.UserHandler
-	ReviewHandler  *api.ReviewHandler
-	TokenHandler   *api.TokenHandler
-	Middleware     middleware.UserMiddleware
-	DB             *sql.DB
+	Logger          *log.Logger
+	ArticleHandler  *api.ArticleHandler
+	UserHandler     *api.UserHandler
+	ReviewHandler   *api.ReviewHandler
+	TokenHandler    *api.TokenHandler
+	TagHandler      *api.TagHandler
+	CategoryHandler *api.CategoryHandler
+	TrashHandler    *api.TrashHandler
+	CommentHandler  *api.CommentHandler
+	ReportHandler   *api.ReportHandler
+	APIKeyHandler   *api.APIKeyHandler
+	Middleware      middleware.UserMiddleware
+	Scheduler       *scheduler.Scheduler
+	Mailer          *mailer.AsyncMailer
+	DB              *sql.DB
 }
 
-func NewApplication() (*Application, error) {
+func NewApplication(cfg Config) (*Application, error) {
 	pgDB, err := store.Open()
 	if err != nil {
 		return nil, err
@@ -36,32 +76,102 @@ func NewApplication() (*Application, error) {
 
 	logger := log.New(os.Stdout, "", log.Ldate|log.Ltime)
 
+	transport, err := newMailer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	appMailer := mailer.NewAsyncMailer(transport, mailWorkers, mailQueueSize, 3, 5*time.Second, logger)
+
 	articleStore := store.NewPostgresArticleStore(pgDB)
 	tokenStore := store.NewPostgresTokenStore(pgDB)
 	userStore := store.NewPostgresUserStore(pgDB)
 	reviewStore := store.NewPostgresReviewStore(pgDB)
+	tagStore := store.NewPostgresTagStore(pgDB)
+	categoryStore := store.NewPostgresCategoryStore(pgDB)
+	trashStore := store.NewPostgresTrashStore(pgDB)
+	commentStore := store.NewPostgresCommentStore(pgDB)
+	reportStore := store.NewPostgresReportStore(pgDB)
+	apiKeyStore := store.NewPostgresAPIKeyStore(pgDB)
 
 	userMiddleware := middleware.UserMiddleware{
-		UserStore: userStore,
+		UserStore:   userStore,
+		TokenStore:  tokenStore,
+		APIKeyStore: apiKeyStore,
+		Logger:      logger,
 	}
 
-	articleHandler := api.NewArticleHandler(articleStore, logger)
-	userHandler := api.NewUserHandler(userStore, tokenStore, logger)
+	articleHandler := api.NewArticleHandler(articleStore, categoryStore, reviewStore, logger)
+	userHandler := api.NewUserHandler(userStore, tokenStore, appMailer, cfg.BaseURL, logger)
 	reviewHandler := api.NewReviewHandler(reviewStore, articleStore, logger)
 	tokenHandler := api.NewTokenHandler(tokenStore, userStore, logger)
+	tagHandler := api.NewTagHandler(tagStore, logger)
+	categoryHandler := api.NewCategoryHandler(categoryStore, logger)
+	trashHandler := api.NewTrashHandler(trashStore, cfg.TrashRetention, logger)
+	commentHandler := api.NewCommentHandler(commentStore, articleStore, logger)
+	reportHandler := api.NewReportHandler(reportStore, articleStore, reviewStore, userStore, logger)
+	apiKeyHandler := api.NewAPIKeyHandler(apiKeyStore, logger)
+
+	jobs := scheduler.NewScheduler(logger)
+	for _, job := range []scheduler.Job{
+		scheduler.PublishScheduledArticles(articleStore, cfg.PublishInterval, logger),
+		scheduler.PurgeTrash(trashStore, cfg.TrashRetention, time.Hour, logger),
+	} {
+		if err := jobs.Add(job); err != nil {
+			pgDB.Close()
+			return nil, err
+		}
+	}
+	jobs.Start()
 
 	app := &Application{
-		Logger:         logger,
-		ArticleHandler: articleHandler,
-		UserHandler:    userHandler,
-		ReviewHandler:  reviewHandler,
-		TokenHandler:   tokenHandler,
-		Middleware:     userMiddleware,
-		DB:             pgDB,
+		Logger:          logger,
+		ArticleHandler:  articleHandler,
+		UserHandler:     userHandler,
+		ReviewHandler:   reviewHandler,
+		TokenHandler:    tokenHandler,
+		TagHandler:      tagHandler,
+		CategoryHandler: categoryHandler,
+		TrashHandler:    trashHandler,
+		CommentHandler:  commentHandler,
+		ReportHandler:   reportHandler,
+		APIKeyHandler:   apiKeyHandler,
+		Middleware:      userMiddleware,
+		Scheduler:       jobs,
+		Mailer:          appMailer,
+		DB:              pgDB,
 	}
 	return app, nil
 }
 
+func newMailer(cfg Config) (mailer.Mailer, error) {
+	if cfg.SMTP.Host != "" {
+		return mailer.NewSMTPMailer(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.Sender)
+	}
+
+	if cfg.MailFile == "" {
+		return mailer.NewLogMailer(os.Stdout, cfg.SMTP.Sender), nil
+	}
+
+	f, err := os.OpenFile(cfg.MailFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening mail file: %w", err)
+	}
+	return mailer.NewLogMailer(f, cfg.SMTP.Sender), nil
+}
+
+// Close stops the background jobs, gives queued emails up to
+// mailShutdownTimeout to go out and then closes the database pool.
+func (a *Application) Close() error {
+	a.Scheduler.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), mailShutdownTimeout)
+	defer cancel()
+	if err := a.Mailer.Close(ctx); err != nil {
+		a.Logger.Printf("ERROR: closing mailer: %v", err)
+	}
+	return a.DB.Close()
+}
+
 func (a *Application) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, "Status is available\n")
 }
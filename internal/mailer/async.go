@@ -0,0 +1,146 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned by AsyncMailer.Send when the mail server can't
+// keep up and the queue has no room left.
+var ErrQueueFull = errors.New("mail queue is full")
+
+// ErrMailerClosed is returned by AsyncMailer.Send after Close.
+var ErrMailerClosed = errors.New("mailer is closed")
+
+// AsyncMailer hands messages to another Mailer from a fixed number of
+// background workers so requests don't wait on the mail server. Failed
+// sends are retried with a growing delay; the last error is only logged.
+type AsyncMailer struct {
+	next     Mailer
+	attempts int
+	backoff  time.Duration
+	logger   *log.Logger
+
+	mu     sync.Mutex
+	closed bool
+	queue  chan *Message
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewAsyncMailer wraps next and starts workers goroutines that take
+// messages from a queue holding up to queueSize of them. A message is
+// tried up to attempts times, the n-th retry waits n times backoff.
+func NewAsyncMailer(next Mailer, workers, queueSize, attempts int, backoff time.Duration, logger *log.Logger) *AsyncMailer {
+	m := &AsyncMailer{
+		next:     next,
+		attempts: max(attempts, 1),
+		backoff:  backoff,
+		logger:   logger,
+		queue:    make(chan *Message, queueSize),
+		stop:     make(chan struct{}),
+	}
+
+	for range max(workers, 1) {
+		m.wg.Add(1)
+		go m.work()
+	}
+	return m
+}
+
+// Send queues msg. It doesn't wait for the message to go out.
+func (m *AsyncMailer) Send(msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrMailerClosed
+	}
+
+	select {
+	case m.queue <- msg:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting messages and waits for the queued ones to be sent
+// or given up on. When ctx ends first, pending retries are abandoned and
+// ctx's error is returned; a send already talking to the server is left to
+// its own timeout.
+func (m *AsyncMailer) Close(ctx context.Context) error {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		m.abandon()
+		return ctx.Err()
+	}
+}
+
+func (m *AsyncMailer) abandon() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case <-m.stop:
+	default:
+		close(m.stop)
+	}
+}
+
+func (m *AsyncMailer) work() {
+	defer m.wg.Done()
+
+	for msg := range m.queue {
+		select {
+		case <-m.stop:
+			return
+		default:
+		}
+		m.deliver(msg)
+	}
+}
+
+func (m *AsyncMailer) deliver(msg *Message) {
+	defer func() {
+		if err := recover(); err != nil {
+			m.logger.Printf("ERROR: mailer: sending %q to %s panicked: %v", msg.Subject, msg.To, err)
+		}
+	}()
+
+	var err error
+	for attempt := 1; attempt <= m.attempts; attempt++ {
+		if err = m.next.Send(msg); err == nil {
+			return
+		}
+		if attempt == m.attempts {
+			break
+		}
+
+		select {
+		case <-m.stop:
+			m.logger.Printf("ERROR: mailer: gave up sending %q to %s at shutdown: %v", msg.Subject, msg.To, err)
+			return
+		case <-time.After(time.Duration(attempt) * m.backoff):
+		}
+	}
+	m.logger.Printf("ERROR: mailer: sending %q to %s failed after %d attempts: %v", msg.Subject, msg.To, m.attempts, err)
+}
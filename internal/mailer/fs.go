@@ -0,0 +1,6 @@
+package mailer
+
+import "embed"
+
+//go:embed templates/*.tmpl
+var FS embed.FS
@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// LogMailer writes every email to w instead of sending it, for example to
+// stdout or a drop file. It is meant for local development.
+type LogMailer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	sender string
+}
+
+func NewLogMailer(w io.Writer, sender string) *LogMailer {
+	return &LogMailer{w: w, sender: sender}
+}
+
+func (m *LogMailer) Send(msg *Message) error {
+	body, err := buildMessage(m.sender, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err = m.w.Write(body); err != nil {
+		return err
+	}
+	_, err = io.WriteString(m.w, "\r\n\r\n")
+	return err
+}
+
+// MemoryMailer keeps sent messages in memory so tests can inspect them.
+type MemoryMailer struct {
+	mu   sync.Mutex
+	sent []*Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (m *MemoryMailer) Send(msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Sent returns a copy of the messages sent so far, oldest first.
+func (m *MemoryMailer) Sent() []*Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Message(nil), m.sent...)
+}
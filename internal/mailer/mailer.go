@@ -0,0 +1,108 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Message is an email ready to be sent. HTMLBody is optional.
+type Message struct {
+	To        string
+	Subject   string
+	PlainBody string
+	HTMLBody  string
+}
+
+// Mailer delivers messages. Implementations must be safe for concurrent use.
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// NewMessage renders the templates/<templateFile> template for recipient.
+// The file defines a "subject", a "plainBody" and an "htmlBody" template;
+// the HTML one is rendered with html/template so data is escaped.
+func NewMessage(recipient, templateFile string, data any) (*Message, error) {
+	plain, err := template.New("email").ParseFS(FS, "templates/"+templateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{To: recipient}
+	if msg.Subject, err = execute(plain, "subject", data); err != nil {
+		return nil, err
+	}
+	if msg.PlainBody, err = execute(plain, "plainBody", data); err != nil {
+		return nil, err
+	}
+
+	html, err := htmltemplate.New("email").ParseFS(FS, "templates/"+templateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	if err = html.ExecuteTemplate(&body, "htmlBody", data); err != nil {
+		return nil, err
+	}
+	msg.HTMLBody = strings.TrimSpace(body.String())
+
+	return msg, nil
+}
+
+func execute(tmpl *template.Template, name string, data any) (string, error) {
+	var b bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&b, name, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// buildMessage encodes msg as a MIME email, multipart/alternative when it
+// has an HTML body.
+func buildMessage(sender string, msg *Message, now time.Time) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", sender)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTMLBody == "" {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+		b.WriteString(crlf(msg.PlainBody))
+		return b.Bytes(), nil
+	}
+
+	w := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", w.Boundary())
+
+	parts := []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", msg.PlainBody},
+		{"text/html; charset=UTF-8", msg.HTMLBody},
+	}
+	for _, part := range parts {
+		pw, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err = pw.Write([]byte(crlf(part.body))); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func crlf(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+}
@@ -0,0 +1,132 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMessage(t *testing.T) {
+	msg, err := NewMessage("noah@example.com", "password_reset.tmpl", map[string]any{
+		"Username": "<noah>",
+		"ResetURL": "https://example.com/reset/abc",
+		"Expiry":   "tomorrow",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "noah@example.com", msg.To)
+	assert.Equal(t, "Reset your password", msg.Subject)
+	assert.Contains(t, msg.PlainBody, "Hi <noah>,")
+	assert.Contains(t, msg.PlainBody, "https://example.com/reset/abc")
+	assert.Contains(t, msg.HTMLBody, "Hi &lt;noah&gt;,")
+	assert.Contains(t, msg.HTMLBody, `<a href="https://example.com/reset/abc">`)
+}
+
+func TestLogMailerSend(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLogMailer(&buf, "Articles <no-reply@example.com>")
+
+	require.NoError(t, m.Send(&Message{To: "noah@example.com", Subject: "Welcome", PlainBody: "line one\nline two"}))
+
+	out := buf.String()
+	assert.Contains(t, out, "From: Articles <no-reply@example.com>\r\n")
+	assert.Contains(t, out, "To: noah@example.com\r\n")
+	assert.Contains(t, out, "Subject: Welcome\r\n")
+	assert.Contains(t, out, "\r\n\r\nline one\r\nline two")
+}
+
+func TestLogMailerSendMultipart(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLogMailer(&buf, "no-reply@example.com")
+
+	require.NoError(t, m.Send(&Message{To: "noah@example.com", Subject: "Welcome", PlainBody: "plain", HTMLBody: "<p>html</p>"}))
+
+	out := buf.String()
+	assert.Contains(t, out, "Content-Type: multipart/alternative; boundary=")
+	assert.Contains(t, out, "Content-Type: text/plain; charset=UTF-8\r\n\r\nplain")
+	assert.Contains(t, out, "Content-Type: text/html; charset=UTF-8\r\n\r\n<p>html</p>")
+}
+
+type flakyMailer struct {
+	mu       sync.Mutex
+	failures int
+	calls    int
+	sent     []*Message
+}
+
+func (m *flakyMailer) Send(msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	if m.calls <= m.failures {
+		return errors.New("connection refused")
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestAsyncMailerRetries(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+
+	next := &flakyMailer{failures: 2}
+	m := NewAsyncMailer(next, 1, 10, 3, 0, logger)
+	require.NoError(t, m.Send(&Message{To: "noah@example.com"}))
+	require.NoError(t, m.Close(context.Background()))
+	assert.Equal(t, 3, next.calls)
+	assert.Len(t, next.sent, 1)
+
+	next = &flakyMailer{failures: 5}
+	m = NewAsyncMailer(next, 1, 10, 3, 0, logger)
+	require.NoError(t, m.Send(&Message{To: "noah@example.com"}))
+	require.NoError(t, m.Close(context.Background()))
+	assert.Equal(t, 3, next.calls)
+	assert.Empty(t, next.sent)
+}
+
+type blockingMailer struct {
+	release chan struct{}
+}
+
+func (m *blockingMailer) Send(msg *Message) error {
+	<-m.release
+	return nil
+}
+
+func TestAsyncMailerQueueIsBounded(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+
+	next := &blockingMailer{release: make(chan struct{})}
+	m := NewAsyncMailer(next, 1, 1, 1, 0, logger)
+
+	// The worker picks up the first message and blocks on it, the second
+	// one fills the queue.
+	require.NoError(t, m.Send(&Message{To: "noah@example.com"}))
+	require.Eventually(t, func() bool { return len(m.queue) == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, m.Send(&Message{To: "noah@example.com"}))
+	assert.ErrorIs(t, m.Send(&Message{To: "noah@example.com"}), ErrQueueFull)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, m.Close(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, m.Send(&Message{To: "noah@example.com"}), ErrMailerClosed)
+
+	close(next.release)
+}
+
+func TestNewSMTPMailerSender(t *testing.T) {
+	m, err := NewSMTPMailer("localhost", 25, "", "", "Articles <no-reply@articles.local>")
+	require.NoError(t, err)
+	assert.Equal(t, "no-reply@articles.local", m.envelopeSender)
+	assert.Equal(t, `"Articles" <no-reply@articles.local>`, m.sender)
+
+	_, err = NewSMTPMailer("localhost", 25, "", "", "not an address")
+	assert.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// smtpTimeout bounds a whole send, from dialing the server to QUIT, so a
+// stalled server can't hold a mail worker forever.
+const smtpTimeout = 30 * time.Second
+
+// SMTPMailer sends emails through an SMTP server using PLAIN auth.
+type SMTPMailer struct {
+	host string
+	addr string
+	auth smtp.Auth
+	// sender is the From header, e.g. "Articles <no-reply@example.com>";
+	// envelopeSender is only the address, which is what MAIL FROM takes.
+	sender         string
+	envelopeSender string
+}
+
+// NewSMTPMailer returns an error when sender isn't a valid address.
+func NewSMTPMailer(host string, port int, username, password, sender string) (*SMTPMailer, error) {
+	from, err := mail.ParseAddress(sender)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender %q: %w", sender, err)
+	}
+
+	m := &SMTPMailer{
+		host:           host,
+		addr:           fmt.Sprintf("%s:%d", host, port),
+		sender:         from.String(),
+		envelopeSender: from.Address,
+	}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m, nil
+}
+
+// Send does what smtp.SendMail does, but over a connection with a deadline.
+func (m *SMTPMailer) Send(msg *Message) error {
+	body, err := buildMessage(m.sender, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{Timeout: smtpTimeout}
+	conn, err := dialer.Dial("tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(smtpTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(m.envelopeSender); err != nil {
+		return err
+	}
+	if err := c.Rcpt(msg.To); err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
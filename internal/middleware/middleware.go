@@ -2,8 +2,12 @@ package middleware
 
 import (
 	"context"
+	"crypto/sha256"
+	"log"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/htojiddinov77-png/Articles/internal/store"
 	"github.com/htojiddinov77-png/Articles/internal/utils"
@@ -12,13 +16,20 @@ import (
 )
 
 type UserMiddleware struct {
-	UserStore store.UserStore
+	UserStore   store.UserStore
+	TokenStore  store.TokenStore
+	APIKeyStore store.APIKeyStore
+	Logger      *log.Logger
 }
 
 type contextKey string // this is for avoiding collision
 
 const UserContextKey = contextKey("user")
 
+// TokenHashContextKey holds the hash of the token the request was
+// authenticated with.
+const TokenHashContextKey = contextKey("token_hash")
+
 func SetUser(r *http.Request, user *store.User) *http.Request {
 	//ctx, key, value
 	ctx := context.WithValue(r.Context(), UserContextKey, user)
@@ -33,6 +44,18 @@ func GetUser(r *http.Request) *store.User {
 	return user
 }
 
+func setTokenHash(r *http.Request, hash []byte) *http.Request {
+	ctx := context.WithValue(r.Context(), TokenHashContextKey, hash)
+	return r.WithContext(ctx)
+}
+
+// GetTokenHash returns the hash of the request's authentication token, or
+// nil for anonymous requests.
+func GetTokenHash(r *http.Request) []byte {
+	hash, _ := r.Context().Value(TokenHashContextKey).([]byte)
+	return hash
+}
+
 func (um *UserMiddleware) Authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Vary", "Authorization")
@@ -50,6 +73,11 @@ func (um *UserMiddleware) Authenticate(next http.Handler) http.Handler {
 		}
 
 		token := headerParts[1]
+		if strings.HasPrefix(token, tokens.APIKeyPrefix) {
+			um.authenticateAPIKey(w, r, next, token)
+			return
+		}
+
 		user, err := um.UserStore.GetUserToken(tokens.ScopeAuth, token)
 		if err != nil {
 			utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "Invalid token"})
@@ -61,12 +89,64 @@ func (um *UserMiddleware) Authenticate(next http.Handler) http.Handler {
 			return 
 		}
 
+		if user.IsLockedOut(time.Now()) {
+			utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": user.LockoutMessage()})
+			return
+		}
+
+		user.Permissions, err = um.UserStore.GetPermissionsForUser(int64(user.ID))
+		if err != nil {
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+			return
+		}
+
+		tokenHash := sha256.Sum256([]byte(token))
+		if err := um.TokenStore.MarkTokenUsed(tokenHash[:]); err != nil {
+			um.Logger.Printf("ERROR: marking token used: %v", err)
+		}
+
 		r = SetUser(r, user)
+		r = setTokenHash(r, tokenHash[:])
 		next.ServeHTTP(w, r)
 		return 
 	})
 }
 
+// authenticateAPIKey authenticates the request as the owner of the key. The
+// user only keeps the permissions that are also scopes of the key.
+func (um *UserMiddleware) authenticateAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, plaintext string) {
+	user, key, err := um.APIKeyStore.GetUserForAPIKey(plaintext)
+	if err != nil {
+		um.Logger.Printf("ERROR: getting user for API key: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if user == nil {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "Invalid or expired API key"})
+		return
+	}
+
+	if user.IsLockedOut(time.Now()) {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": user.LockoutMessage()})
+		return
+	}
+
+	permissions, err := um.UserStore.GetPermissionsForUser(int64(user.ID))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	user.Permissions = permissions.Intersect(key.Scopes)
+	user.APIKey = key
+
+	if err := um.APIKeyStore.MarkAPIKeyUsed(key.ID); err != nil {
+		um.Logger.Printf("ERROR: marking API key used: %v", err)
+	}
+
+	next.ServeHTTP(w, SetUser(r, user))
+}
+
 func (um *UserMiddleware) RequireUser(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := GetUser(r)
@@ -78,4 +158,80 @@ func (um *UserMiddleware) RequireUser(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 		return 
 	})
-}
\ No newline at end of file
+}
+
+// RequireActivatedUser only lets users that have verified their email
+// address through.
+func (um *UserMiddleware) RequireActivatedUser(next http.Handler) http.Handler {
+	return um.RequireUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := GetUser(r)
+		if !user.Activated {
+			utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you must activate your account to access this resource"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// RequireScope stops requests made with an API key that wasn't given
+// scope. Anonymous requests and sessions are let through.
+func (um *UserMiddleware) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetUser(r)
+			if !user.HasScope(scope) {
+				utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "this API key doesn't have the " + scope + " scope"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession only lets logged in users through that didn't use an API
+// key, for account and credential management.
+func (um *UserMiddleware) RequireSession(next http.Handler) http.Handler {
+	return um.RequireUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := GetUser(r)
+		if user.APIKey != nil {
+			utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "API keys can't be used for this resource, log in instead"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// RequirePermission only lets the request through when the authenticated
+// user's role grants the given permission.
+func (um *UserMiddleware) RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return um.RequireUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetUser(r)
+			if !user.Can(permission) {
+				utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you don't have permission to access this resource"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// RequireRole only lets the request through when the authenticated user has
+// one of the given roles.
+func (um *UserMiddleware) RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return um.RequireUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetUser(r)
+			if !slices.Contains(roles, user.Role) {
+				utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you don't have permission to access this resource"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
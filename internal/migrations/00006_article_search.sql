@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+ALTER TABLE paragraphs ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(headline, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(body, '')), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS articles_search_idx ON articles USING GIN (search);
+CREATE INDEX IF NOT EXISTS paragraphs_search_idx ON paragraphs USING GIN (search);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS paragraphs_search_idx;
+DROP INDEX IF EXISTS articles_search_idx;
+ALTER TABLE paragraphs DROP COLUMN IF EXISTS search;
+ALTER TABLE articles DROP COLUMN IF EXISTS search;
+-- +goose StatementEnd
@@ -0,0 +1,49 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE IF NOT EXISTS roles (
+    name TEXT PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    code TEXT PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS roles_permissions (
+    role TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission TEXT NOT NULL REFERENCES permissions(code) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name) VALUES ('reader'), ('author'), ('moderator'), ('admin');
+
+INSERT INTO permissions (code) VALUES
+    ('articles:write'),
+    ('articles:manage'),
+    ('reviews:write'),
+    ('reviews:manage'),
+    ('users:manage');
+
+INSERT INTO roles_permissions (role, permission) VALUES
+    ('reader', 'reviews:write'),
+    ('author', 'reviews:write'),
+    ('author', 'articles:write'),
+    ('moderator', 'reviews:write'),
+    ('moderator', 'articles:write'),
+    ('moderator', 'reviews:manage'),
+    ('moderator', 'articles:manage');
+
+INSERT INTO roles_permissions (role, permission)
+SELECT 'admin', code FROM permissions;
+
+-- everybody could write articles before roles existed, keep it that way
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'author' REFERENCES roles(name);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+DROP TABLE roles_permissions;
+DROP TABLE permissions;
+DROP TABLE roles;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE articles
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'draft',
+    ADD COLUMN IF NOT EXISTS published_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE articles ADD CONSTRAINT articles_status_check
+    CHECK (status IN ('draft', 'in_review', 'published', 'archived'));
+
+-- everything written before the workflow existed was already public
+UPDATE articles SET status = 'published', published_at = created_at;
+
+CREATE INDEX IF NOT EXISTS articles_status_idx ON articles(status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS articles_status_idx;
+ALTER TABLE articles DROP CONSTRAINT IF EXISTS articles_status_check;
+ALTER TABLE articles DROP COLUMN IF EXISTS published_at;
+ALTER TABLE articles DROP COLUMN IF EXISTS status;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE articles DROP CONSTRAINT IF EXISTS articles_status_check;
+ALTER TABLE articles ADD CONSTRAINT articles_status_check
+    CHECK (status IN ('draft', 'in_review', 'scheduled', 'published', 'archived'));
+
+ALTER TABLE articles ADD CONSTRAINT articles_publish_at_check
+    CHECK (status <> 'scheduled' OR publish_at IS NOT NULL);
+
+CREATE INDEX IF NOT EXISTS articles_publish_at_idx ON articles(publish_at) WHERE status = 'scheduled';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS articles_publish_at_idx;
+ALTER TABLE articles DROP CONSTRAINT IF EXISTS articles_publish_at_check;
+UPDATE articles SET status = 'draft' WHERE status = 'scheduled';
+ALTER TABLE articles DROP CONSTRAINT IF EXISTS articles_status_check;
+ALTER TABLE articles ADD CONSTRAINT articles_status_check
+    CHECK (status IN ('draft', 'in_review', 'published', 'archived'));
+ALTER TABLE articles DROP COLUMN IF EXISTS publish_at;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS version;
+ALTER TABLE reviews DROP COLUMN IF EXISTS version;
+ALTER TABLE articles DROP COLUMN IF EXISTS version;
+-- +goose StatementEnd
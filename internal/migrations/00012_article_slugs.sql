@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS slug VARCHAR(255);
+
+-- existing articles get an ASCII-only slug made unique with their id
+UPDATE articles
+SET slug = trim(both '-' from regexp_replace(lower(title), '[^a-z0-9]+', '-', 'g') || '-' || id);
+
+ALTER TABLE articles ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE articles ADD CONSTRAINT articles_slug_key UNIQUE (slug);
+
+-- previous slugs of renamed articles, kept so old links keep working
+CREATE TABLE IF NOT EXISTS article_slugs (
+    slug VARCHAR(255) PRIMARY KEY,
+    article_id BIGINT NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS article_slugs_article_id_idx ON article_slugs(article_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE article_slugs;
+ALTER TABLE articles DROP CONSTRAINT IF EXISTS articles_slug_key;
+ALTER TABLE articles DROP COLUMN IF EXISTS slug;
+-- +goose StatementEnd
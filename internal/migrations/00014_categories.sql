@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE IF NOT EXISTS categories (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    parent_id BIGINT REFERENCES categories(id) ON DELETE RESTRICT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT categories_parent_check CHECK (parent_id <> id)
+);
+
+-- sibling categories must have different names, top level ones included
+CREATE UNIQUE INDEX IF NOT EXISTS categories_parent_name_idx ON categories (COALESCE(parent_id, 0), lower(name));
+
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS category_id BIGINT REFERENCES categories(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS articles_category_id_idx ON articles(category_id);
+
+INSERT INTO permissions (code) VALUES ('categories:manage');
+INSERT INTO roles_permissions (role, permission) VALUES ('admin', 'categories:manage');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE code = 'categories:manage';
+ALTER TABLE articles DROP COLUMN IF EXISTS category_id;
+DROP TABLE categories;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- the trash listing and the purge job only ever look at deleted rows
+CREATE INDEX IF NOT EXISTS articles_deleted_at_idx ON articles(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS reviews_deleted_at_idx ON reviews(deleted_at) WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM reviews WHERE deleted_at IS NOT NULL;
+DELETE FROM articles WHERE deleted_at IS NOT NULL;
+ALTER TABLE reviews DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE articles DROP COLUMN IF EXISTS deleted_at;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- keep the latest review of every user on an article, the older duplicates
+-- go to the trash where their authors can still find them
+UPDATE reviews r
+SET deleted_at = NOW()
+WHERE r.deleted_at IS NULL AND EXISTS (
+    SELECT 1 FROM reviews newer
+    WHERE newer.user_id = r.user_id
+      AND newer.article_id = r.article_id
+      AND newer.deleted_at IS NULL
+      AND (newer.created_at, newer.id) > (r.created_at, r.id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS reviews_user_article_idx ON reviews (user_id, article_id) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS reviews_user_article_idx;
+-- +goose StatementEnd
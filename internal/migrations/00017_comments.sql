@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE IF NOT EXISTS comments (
+    id BIGSERIAL PRIMARY KEY,
+    article_id BIGINT NOT NULL REFERENCES articles(id) ON DELETE CASCADE,
+    parent_id BIGINT REFERENCES comments(id) ON DELETE CASCADE,
+    -- deleting an account keeps the replies to its comments readable
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS comments_article_id_idx ON comments(article_id);
+CREATE INDEX IF NOT EXISTS comments_parent_id_idx ON comments(parent_id);
+
+INSERT INTO permissions (code) VALUES ('comments:write'), ('comments:manage');
+
+INSERT INTO roles_permissions (role, permission) VALUES
+    ('reader', 'comments:write'),
+    ('author', 'comments:write'),
+    ('moderator', 'comments:write'),
+    ('moderator', 'comments:manage'),
+    ('admin', 'comments:write'),
+    ('admin', 'comments:manage');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE code IN ('comments:write', 'comments:manage');
+DROP TABLE comments;
+-- +goose StatementEnd
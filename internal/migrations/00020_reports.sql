@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TABLE IF NOT EXISTS reports (
+    id BIGSERIAL PRIMARY KEY,
+    reporter_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_type TEXT NOT NULL,
+    target_id BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open',
+    action TEXT,
+    resolved_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT reports_target_type_check CHECK (target_type IN ('article', 'review', 'user')),
+    CONSTRAINT reports_status_check CHECK (status IN ('open', 'resolved')),
+    CONSTRAINT reports_action_check CHECK (action IN ('dismiss', 'hide_content', 'suspend_user'))
+);
+
+-- a user can only have one open report on the same thing
+CREATE UNIQUE INDEX IF NOT EXISTS reports_open_reporter_target_idx ON reports (reporter_id, target_type, target_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS reports_open_target_idx ON reports (target_type, target_id) WHERE status = 'open';
+
+ALTER TABLE articles ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE users ADD CONSTRAINT users_status_check CHECK (status IN ('active', 'suspended'));
+
+INSERT INTO permissions (code) VALUES ('reports:manage');
+INSERT INTO roles_permissions (role, permission) VALUES
+    ('moderator', 'reports:manage'),
+    ('admin', 'reports:manage');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permissions WHERE code = 'reports:manage';
+ALTER TABLE users DROP COLUMN IF EXISTS status;
+ALTER TABLE reviews DROP COLUMN IF EXISTS hidden_at;
+ALTER TABLE articles DROP COLUMN IF EXISTS hidden_at;
+DROP TABLE reports;
+-- +goose StatementEnd
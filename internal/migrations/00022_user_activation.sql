@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN IF NOT EXISTS activated BOOLEAN NOT NULL DEFAULT false;
+
+-- accounts created before email verification existed keep working
+UPDATE users SET activated = true;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM tokens WHERE scope = 'activation';
+ALTER TABLE users DROP COLUMN IF EXISTS activated;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- tokens issued by one login share a family, rotating a refresh token keeps
+-- the family and reusing one revokes it
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS family_id TEXT;
+ALTER TABLE tokens ADD COLUMN IF NOT EXISTS used_at TIMESTAMP(0) WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_tokens_family_id ON tokens (family_id) WHERE family_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM tokens WHERE scope = 'refresh';
+DROP INDEX IF EXISTS idx_tokens_family_id;
+ALTER TABLE tokens DROP COLUMN IF EXISTS used_at;
+ALTER TABLE tokens DROP COLUMN IF EXISTS family_id;
+-- +goose StatementEnd
@@ -3,6 +3,7 @@ package routes
 import (
 	"github.com/go-chi/chi/v5"
 	"github.com/htojiddinov77-png/Articles/internal/app"
+	"github.com/htojiddinov77-png/Articles/internal/store"
 )
 
 func SetupRoutes(app *app.Application) *chi.Mux {
@@ -15,14 +16,38 @@ func SetupRoutes(app *app.Application) *chi.Mux {
 	// PUBLIC ROUTES (no login required) 
 	r.Get("/health", app.HealthCheck)
 
-	r.Get("/articles/{id}", app.ArticleHandler.HandlerGetArticleById)
-	r.Get("/reviews/{id}", app.ReviewHandler.HandleGetReviewByid)
+	// API keys need a read scope, everyone else reads freely
+	r.Group(func(r chi.Router) {
+		r.Use(app.Middleware.RequireScope(store.ScopeArticlesRead))
+		r.Get("/articles", app.ArticleHandler.HandleListArticles)
+		r.Get("/articles/search", app.ArticleHandler.HandleSearchArticles)
+		r.Get("/articles/{id}", app.ArticleHandler.HandlerGetArticleById)
+		r.Get("/articles/by-slug/{slug}", app.ArticleHandler.HandleGetArticleBySlug)
+		r.Get("/articles/{id}/comments", app.CommentHandler.HandleListArticleComments)
+		r.Get("/tags/{name}/articles", app.ArticleHandler.HandleListArticlesByTag)
+		r.Get("/categories/{id}/articles", app.ArticleHandler.HandleListArticlesByCategory)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(app.Middleware.RequireScope(store.ScopeReviewsRead))
+		r.Get("/articles/{id}/reviews", app.ReviewHandler.HandleListArticleReviews)
+		r.Get("/reviews/{id}", app.ReviewHandler.HandleGetReviewByid)
+	})
+
+	r.Get("/tags", app.TagHandler.HandleListTags)
+
+	r.Get("/categories", app.CategoryHandler.HandleListCategories)
+	r.Get("/categories/{id}", app.CategoryHandler.HandleGetCategoryById)
 
 	r.Post("/users/register/", app.UserHandler.HandleRegisterUser)
+	r.Post("/users/activate", app.UserHandler.HandleActivateUser)
+	r.Post("/users/activation-token", app.UserHandler.HandleResendActivation)
 	r.Post("/tokens/authentication", app.TokenHandler.HandleCreateToken)
+	r.Post("/tokens/refresh", app.TokenHandler.HandleRefreshToken)
 	// // user password change
 	r.Post("/users/{id}/password-change/", app.UserHandler.HandleChangePassword)        // password change
 	r.Post("/users/password-reset-request", app.UserHandler.HandlePasswordResetRequest) // password reset requst
+	r.Get("/users/password-reset/{token}", app.UserHandler.HandlePasswordResetForm)     // page the reset email links to
 	r.Post("/users/password-reset/{token}", app.UserHandler.HandlePasswordReset)        // password reset
 
 
@@ -30,18 +55,98 @@ func SetupRoutes(app *app.Application) *chi.Mux {
 	r.Group(func(r chi.Router){
 
 		r.Use(app.Middleware.RequireUser)
-		r.Post("/articles", app.ArticleHandler.HandlerCreateArticle)
-		r.Put("/articles/{id}", app.ArticleHandler.HandleUpdateArticleById)
-		r.Delete("/articles/{id}", app.ArticleHandler.HandleDeleteArticlebyId)
-
-		
-		r.Get("/users/{id}", app.UserHandler.HandleGetUserById)
-		r.Put("/users/{id}", app.UserHandler.HandleUpdateUser)
-		r.Delete("/users/{id}", app.UserHandler.HandleDeleteUser)
-
-		r.Post("/reviews", app.ReviewHandler.HandleCreateReview)
-		r.Put("/reviews/{id}", app.ReviewHandler.HandleUpdateReviewById)
-		r.Delete("/reviews/{id}", app.ReviewHandler.HandleDeleteReview)
+
+		// account and credential management can't be done with an API key
+		r.Group(func(r chi.Router) {
+			r.Use(app.Middleware.RequireSession)
+			r.Get("/users/{id}", app.UserHandler.HandleGetUserById)
+			r.Put("/users/{id}", app.UserHandler.HandleUpdateUser)
+			r.Delete("/users/{id}", app.UserHandler.HandleDeleteUser)
+
+			r.Delete("/tokens/authentication", app.TokenHandler.HandleDeleteToken)
+			r.Get("/users/me/sessions", app.TokenHandler.HandleListSessions)
+			r.Delete("/users/me/sessions/{id}", app.TokenHandler.HandleDeleteSession)
+
+			r.Post("/users/me/api-keys", app.APIKeyHandler.HandleCreateAPIKey)
+			r.Get("/users/me/api-keys", app.APIKeyHandler.HandleListAPIKeys)
+			r.Delete("/users/me/api-keys/{id}", app.APIKeyHandler.HandleDeleteAPIKey)
+
+			r.Get("/trash", app.TrashHandler.HandleListTrash)
+			r.Post("/reports", app.ReportHandler.HandleCreateReport)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.Middleware.RequireScope(store.PermissionReviewsWrite))
+			r.Post("/reviews/{id}/votes", app.ReviewHandler.HandleVoteReview)
+			r.Delete("/reviews/{id}/votes", app.ReviewHandler.HandleDeleteReviewVote)
+
+			// only the author of the reviewed article gets past the handler's check
+			r.Post("/reviews/{id}/response", app.ReviewHandler.HandleCreateReviewResponse)
+			r.Put("/reviews/{id}/response", app.ReviewHandler.HandleUpdateReviewResponse)
+			r.Delete("/reviews/{id}/response", app.ReviewHandler.HandleDeleteReviewResponse)
+		})
+
+		// owners can change their own articles, articles:manage lets staff change any of them
+		r.Group(func(r chi.Router) {
+			r.Use(app.Middleware.RequirePermission(store.PermissionArticlesWrite))
+			r.With(app.Middleware.RequireActivatedUser).Post("/articles", app.ArticleHandler.HandlerCreateArticle)
+			r.Put("/articles/{id}", app.ArticleHandler.HandleUpdateArticleById)
+			r.Delete("/articles/{id}", app.ArticleHandler.HandleDeleteArticlebyId)
+			r.Post("/articles/{id}/restore", app.ArticleHandler.HandleRestoreArticle)
+
+			r.Post("/articles/{id}/submit", app.ArticleHandler.HandleSubmitArticle)
+			r.Post("/articles/{id}/schedule", app.ArticleHandler.HandleScheduleArticle)
+			r.Post("/articles/{id}/publish", app.ArticleHandler.HandlePublishArticle)
+			r.Post("/articles/{id}/unpublish", app.ArticleHandler.HandleUnpublishArticle)
+			r.Post("/articles/{id}/archive", app.ArticleHandler.HandleArchiveArticle)
+
+			r.Post("/articles/{id}/paragraphs", app.ArticleHandler.HandleCreateParagraph)
+			r.Post("/articles/{id}/paragraphs/reorder", app.ArticleHandler.HandleReorderParagraphs)
+			r.Put("/articles/{id}/paragraphs/{pid}", app.ArticleHandler.HandleUpdateParagraph)
+			r.Delete("/articles/{id}/paragraphs/{pid}", app.ArticleHandler.HandleDeleteParagraph)
+
+			r.Get("/articles/{id}/revisions", app.ArticleHandler.HandleListRevisions)
+			r.Get("/articles/{id}/revisions/diff", app.ArticleHandler.HandleDiffRevisions)
+			r.Get("/articles/{id}/revisions/{rev}", app.ArticleHandler.HandleGetRevision)
+			r.Post("/articles/{id}/revisions/{rev}/restore", app.ArticleHandler.HandleRestoreRevision)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.Middleware.RequirePermission(store.PermissionReviewsWrite))
+			r.With(app.Middleware.RequireActivatedUser).Post("/reviews", app.ReviewHandler.HandleCreateReview)
+			r.Put("/reviews/{id}", app.ReviewHandler.HandleUpdateReviewById)
+			r.Delete("/reviews/{id}", app.ReviewHandler.HandleDeleteReview)
+			r.Post("/reviews/{id}/restore", app.ReviewHandler.HandleRestoreReview)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.Middleware.RequirePermission(store.PermissionCommentsWrite))
+			r.Post("/articles/{id}/comments", app.CommentHandler.HandleCreateComment)
+			r.Put("/comments/{id}", app.CommentHandler.HandleUpdateComment)
+			r.Delete("/comments/{id}", app.CommentHandler.HandleDeleteComment)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.Middleware.RequirePermission(store.PermissionUsersManage))
+			r.Put("/users/{id}/role", app.UserHandler.HandleUpdateUserRole)
+			r.Post("/users/{id}/suspend", app.UserHandler.HandleSuspendUser)
+			r.Post("/users/{id}/unsuspend", app.UserHandler.HandleUnsuspendUser)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.Middleware.RequirePermission(store.PermissionReportsManage))
+			r.Get("/moderation/queue", app.ReportHandler.HandleModerationQueue)
+			r.Post("/reports/{id}/resolve", app.ReportHandler.HandleResolveReport)
+			r.Post("/articles/{id}/unhide", app.ReportHandler.HandleUnhideArticle)
+			r.Post("/reviews/{id}/unhide", app.ReportHandler.HandleUnhideReview)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.Middleware.RequirePermission(store.PermissionCategoriesManage))
+			r.Post("/categories", app.CategoryHandler.HandleCreateCategory)
+			r.Put("/categories/{id}", app.CategoryHandler.HandleUpdateCategory)
+			r.Delete("/categories/{id}", app.CategoryHandler.HandleDeleteCategory)
+		})
 	})
 
 	return r
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/htojiddinov77-png/Articles/internal/api"
+	"github.com/htojiddinov77-png/Articles/internal/app"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordResetLinkAcceptsGet(t *testing.T) {
+	r := SetupRoutes(&app.Application{})
+	path := api.PasswordResetPath("ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+
+	// the link in the email is opened in a browser, the form on that page
+	// posts back to the same address
+	assert.True(t, r.Match(chi.NewRouteContext(), http.MethodGet, path))
+	assert.True(t, r.Match(chi.NewRouteContext(), http.MethodPost, path))
+}
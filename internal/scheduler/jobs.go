@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/htojiddinov77-png/Articles/internal/store"
+)
+
+// publishBatchSize caps how many articles one poll publishes, so a backlog
+// is worked off over several ticks instead of one long transaction.
+const publishBatchSize = 100
+
+// PurgeTrash permanently deletes articles and reviews that have been in the
+// trash for longer than retention.
+func PurgeTrash(trashStore store.TrashStore, retention, interval time.Duration, logger *log.Logger) Job {
+	return Job{
+		Name:     "purge-trash",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			purged, err := trashStore.PurgeTrash(time.Now().Add(-retention))
+			if err != nil {
+				return err
+			}
+
+			if purged > 0 {
+				logger.Printf("purged %d items from the trash", purged)
+			}
+			return nil
+		},
+	}
+}
+
+// PublishScheduledArticles publishes every scheduled article whose
+// publish_at has passed.
+func PublishScheduledArticles(articleStore store.ArticleStore, interval time.Duration, logger *log.Logger) Job {
+	return Job{
+		Name:     "publish-scheduled-articles",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			for ctx.Err() == nil {
+				ids, err := articleStore.PublishDueArticles(time.Now(), publishBatchSize)
+				if err != nil {
+					return err
+				}
+
+				for _, id := range ids {
+					logger.Printf("published scheduled article %d", id)
+				}
+
+				if len(ids) < publishBatchSize {
+					return nil
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is a unit of background work the scheduler runs every Interval.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs jobs in background goroutines until Stop is called. Jobs
+// must be safe to run from several server instances at once; coordination
+// happens in the database, not here.
+type Scheduler struct {
+	jobs   []Job
+	logger *log.Logger
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewScheduler(logger *log.Logger) *Scheduler {
+	return &Scheduler{logger: logger}
+}
+
+// Add registers a job. It has no effect once the scheduler is started. Jobs
+// need a positive interval.
+func (s *Scheduler) Add(job Job) error {
+	if job.Interval <= 0 {
+		return fmt.Errorf("scheduler job %s: interval must be positive, got %s", job.Name, job.Interval)
+	}
+
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+}
+
+// Stop signals every job to finish and waits for the running ones to return.
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.run(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	defer func() {
+		if err := recover(); err != nil {
+			s.logger.Printf("ERROR: scheduler job %s panicked: %v", job.Name, err)
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil {
+		s.logger.Printf("ERROR: scheduler job %s: %v", job.Name, err)
+	}
+}
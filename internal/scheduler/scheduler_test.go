@@ -0,0 +1,50 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedulerRunsJobsUntilStopped(t *testing.T) {
+	s := NewScheduler(log.New(io.Discard, "", 0))
+
+	var runs atomic.Int32
+	err := s.Add(Job{
+		Name:     "counter",
+		Interval: time.Millisecond,
+		Run: func(ctx context.Context) error {
+			runs.Add(1)
+			return errors.New("errors are logged, not fatal")
+		},
+	})
+	require.NoError(t, err)
+
+	s.Start()
+	assert.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, time.Millisecond)
+	s.Stop()
+
+	stopped := runs.Load()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, stopped, runs.Load(), "jobs must not run after Stop returns")
+}
+
+func TestSchedulerRejectsNonPositiveInterval(t *testing.T) {
+	s := NewScheduler(log.New(io.Discard, "", 0))
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		err := s.Add(Job{Name: "broken", Interval: interval, Run: func(ctx context.Context) error { return nil }})
+		assert.Error(t, err)
+	}
+
+	// nothing was registered, so starting and stopping must not panic
+	s.Start()
+	s.Stop()
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgtype"
+)
+
+// Read scopes only exist for API keys. The other scopes are permission
+// codes, and a key never grants more than its owner's role does.
+const (
+	ScopeArticlesRead = "articles:read"
+	ScopeReviewsRead  = "reviews:read"
+)
+
+var readScopes = []string{ScopeArticlesRead, ScopeReviewsRead}
+
+// ValidateAPIKeyScopes checks that scopes is a non-empty list of read scopes
+// and permissions the owner has. It returns them sorted and without
+// duplicates.
+func ValidateAPIKeyScopes(scopes []string, owner Permissions) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required")
+	}
+
+	valid := []string{}
+	for _, scope := range scopes {
+		if !slices.Contains(readScopes, scope) && !owner.Include(scope) {
+			return nil, fmt.Errorf("invalid scope %q", scope)
+		}
+		if !slices.Contains(valid, scope) {
+			valid = append(valid, scope)
+		}
+	}
+
+	sort.Strings(valid)
+	return valid, nil
+}
+
+// APIKey is a long-lived credential a user creates for scripts and CI. The
+// plaintext key is only known when it is created.
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// HasScope reports whether the key was given scope.
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+const apiKeyColumns = `k.id, k.user_id, k.name, k.prefix, k.scopes, k.expires_at, k.last_used_at, k.created_at`
+
+func (k *APIKey) scanDest() []any {
+	return []any{
+		&k.ID,
+		&k.UserID,
+		&k.Name,
+		&k.Prefix,
+		(*textArray)(&k.Scopes),
+		&k.ExpiresAt,
+		&k.LastUsedAt,
+		&k.CreatedAt,
+	}
+}
+
+type PostgresAPIKeyStore struct {
+	db *sql.DB
+}
+
+func NewPostgresAPIKeyStore(db *sql.DB) *PostgresAPIKeyStore {
+	return &PostgresAPIKeyStore{db: db}
+}
+
+type APIKeyStore interface {
+	CreateAPIKey(key *APIKey, hash []byte) error
+	ListAPIKeys(userID int) ([]*APIKey, error)
+	DeleteAPIKey(userID int, id int64) error
+	GetUserForAPIKey(plaintext string) (*User, *APIKey, error)
+	MarkAPIKeyUsed(id int) error
+}
+
+func (pg *PostgresAPIKeyStore) CreateAPIKey(key *APIKey, hash []byte) error {
+	var scopes pgtype.TextArray
+	if err := scopes.Set(key.Scopes); err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO api_keys (user_id, name, hash, prefix, scopes, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at`
+
+	return pg.db.QueryRow(query, key.UserID, key.Name, hash, key.Prefix, &scopes, key.ExpiresAt).Scan(&key.ID, &key.CreatedAt)
+}
+
+func (pg *PostgresAPIKeyStore) ListAPIKeys(userID int) ([]*APIKey, error) {
+	query := `
+	SELECT ` + apiKeyColumns + `
+	FROM api_keys k
+	WHERE k.user_id = $1
+	ORDER BY k.created_at DESC, k.id DESC`
+
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+	for rows.Next() {
+		key := &APIKey{}
+		if err := rows.Scan(key.scanDest()...); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// DeleteAPIKey revokes one of the user's keys. It returns sql.ErrNoRows when
+// the user has no such key.
+func (pg *PostgresAPIKeyStore) DeleteAPIKey(userID int, id int64) error {
+	return execOne(pg.db, `DELETE FROM api_keys WHERE id = $1 AND user_id = $2`, id, userID)
+}
+
+// GetUserForAPIKey returns the owner of an unexpired key together with the
+// key, or nil, nil when the key is unknown or expired.
+func (pg *PostgresAPIKeyStore) GetUserForAPIKey(plaintext string) (*User, *APIKey, error) {
+	hash := sha256.Sum256([]byte(plaintext))
+
+	query := `
+	SELECT ` + userColumns + `, ` + apiKeyColumns + `
+	FROM api_keys k
+	INNER JOIN users u ON u.id = k.user_id
+	WHERE k.hash = $1 AND (k.expires_at IS NULL OR k.expires_at > NOW())`
+
+	user := &User{}
+	key := &APIKey{}
+	err := pg.db.QueryRow(query, hash[:]).Scan(append(user.scanDest(), key.scanDest()...)...)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, key, nil
+}
+
+// MarkAPIKeyUsed records that the key was just used, at most once a minute.
+func (pg *PostgresAPIKeyStore) MarkAPIKeyUsed(id int) error {
+	query := `
+	UPDATE api_keys
+	SET last_used_at = NOW()
+	WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`
+
+	_, err := pg.db.Exec(query, id)
+	return err
+}
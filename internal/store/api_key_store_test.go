@@ -0,0 +1,31 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAPIKeyScopes(t *testing.T) {
+	owner := Permissions{PermissionArticlesWrite, PermissionReviewsWrite}
+
+	scopes, err := ValidateAPIKeyScopes([]string{PermissionArticlesWrite, ScopeArticlesRead, PermissionArticlesWrite}, owner)
+	require.NoError(t, err)
+	assert.Equal(t, []string{ScopeArticlesRead, PermissionArticlesWrite}, scopes)
+
+	_, err = ValidateAPIKeyScopes(nil, owner)
+	assert.Error(t, err)
+
+	_, err = ValidateAPIKeyScopes([]string{PermissionUsersManage}, owner)
+	assert.Error(t, err)
+
+	_, err = ValidateAPIKeyScopes([]string{"articles:everything"}, owner)
+	assert.Error(t, err)
+}
+
+func TestPermissionsIntersect(t *testing.T) {
+	p := Permissions{PermissionArticlesWrite, PermissionReviewsWrite, PermissionCommentsWrite}
+	assert.Equal(t, Permissions{PermissionArticlesWrite, PermissionCommentsWrite}, p.Intersect([]string{PermissionCommentsWrite, ScopeArticlesRead, PermissionArticlesWrite}))
+	assert.Empty(t, p.Intersect(nil))
+}
@@ -0,0 +1,251 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+)
+
+var (
+	ErrParagraphNotFound = errors.New("paragraph not found")
+	ErrParagraphMismatch = errors.New("paragraph ids must list every paragraph of the article exactly once")
+)
+
+// touchArticle records a change to the article's paragraphs on the article
+// row itself, with the same version check UpdateArticle does.
+func touchArticle(tx *sql.Tx, article *Article) error {
+	query := `
+	UPDATE articles
+	SET updated_at = NOW(), version = version + 1
+	WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+	RETURNING version, updated_at`
+
+	err := tx.QueryRow(query, article.ID, article.Version).Scan(&article.Version, &article.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrEditConflict
+	}
+	return err
+}
+
+func loadParagraphIDs(tx *sql.Tx, articleID int) ([]int, error) {
+	rows, err := tx.Query(`SELECT id FROM paragraphs WHERE article_id = $1 ORDER BY order_index, id`, articleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// syncParagraphs makes the stored paragraphs match article.Paragraphs.
+// Paragraphs that carry the id of an existing paragraph are updated in
+// place, keeping their id and created_at; the rest are inserted and any
+// stored paragraph left out is deleted.
+func syncParagraphs(tx *sql.Tx, article *Article) error {
+	existing, err := loadParagraphIDs(tx, article.ID)
+	if err != nil {
+		return err
+	}
+
+	var kept []int
+	for i := range article.Paragraphs {
+		paragraph := &article.Paragraphs[i]
+
+		if paragraph.ID != 0 && slices.Contains(existing, paragraph.ID) && !slices.Contains(kept, paragraph.ID) {
+			query := `
+			UPDATE paragraphs
+			SET headline = $1, body = $2, order_index = $3,
+				updated_at = CASE WHEN headline IS DISTINCT FROM $1 OR body IS DISTINCT FROM $2
+					THEN NOW() ELSE updated_at END
+			WHERE id = $4 AND article_id = $5
+			RETURNING created_at, updated_at`
+
+			err := tx.QueryRow(query, paragraph.Headline, paragraph.Body, paragraph.OrderIndex, paragraph.ID, article.ID).
+				Scan(&paragraph.CreatedAt, &paragraph.UpdatedAt)
+			if err != nil {
+				return err
+			}
+			kept = append(kept, paragraph.ID)
+			continue
+		}
+
+		query := `
+		INSERT INTO paragraphs (article_id, headline, body, order_index)
+		VALUES($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at`
+
+		err := tx.QueryRow(query, article.ID, paragraph.Headline, paragraph.Body, paragraph.OrderIndex).
+			Scan(&paragraph.ID, &paragraph.CreatedAt, &paragraph.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		kept = append(kept, paragraph.ID)
+	}
+
+	for _, id := range existing {
+		if slices.Contains(kept, id) {
+			continue
+		}
+		_, err := tx.Exec(`DELETE FROM paragraphs WHERE id = $1`, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreateParagraph adds a paragraph to the article. A zero OrderIndex puts it
+// after the last paragraph.
+func (pg *PostgresArticleStore) CreateParagraph(article *Article, paragraph *Paragraph, editorID int) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = snapshotArticle(tx, article.ID, editorID)
+	if err != nil {
+		return err
+	}
+
+	err = touchArticle(tx, article)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO paragraphs (article_id, headline, body, order_index)
+	VALUES($1, $2, $3, CASE WHEN $4 > 0 THEN $4
+		ELSE (SELECT COALESCE(MAX(order_index), 0) + 1 FROM paragraphs WHERE article_id = $1) END)
+	RETURNING id, order_index, created_at, updated_at`
+
+	err = tx.QueryRow(query, article.ID, paragraph.Headline, paragraph.Body, paragraph.OrderIndex).
+		Scan(&paragraph.ID, &paragraph.OrderIndex, &paragraph.CreatedAt, &paragraph.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UpdateParagraph changes the headline and body of one paragraph. Its
+// position only changes through ReorderParagraphs.
+func (pg *PostgresArticleStore) UpdateParagraph(article *Article, paragraph *Paragraph, editorID int) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = snapshotArticle(tx, article.ID, editorID)
+	if err != nil {
+		return err
+	}
+
+	err = touchArticle(tx, article)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE paragraphs
+	SET headline = $1, body = $2, updated_at = NOW()
+	WHERE id = $3 AND article_id = $4
+	RETURNING order_index, created_at, updated_at`
+
+	err = tx.QueryRow(query, paragraph.Headline, paragraph.Body, paragraph.ID, article.ID).
+		Scan(&paragraph.OrderIndex, &paragraph.CreatedAt, &paragraph.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrParagraphNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (pg *PostgresArticleStore) DeleteParagraph(article *Article, paragraphID int, editorID int) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = snapshotArticle(tx, article.ID, editorID)
+	if err != nil {
+		return err
+	}
+
+	err = touchArticle(tx, article)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM paragraphs WHERE id = $1 AND article_id = $2`, paragraphID, article.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrParagraphNotFound
+	}
+
+	return tx.Commit()
+}
+
+// ReorderParagraphs rewrites order_index so the paragraphs follow the order
+// of paragraphIDs, which must hold every paragraph of the article once.
+func (pg *PostgresArticleStore) ReorderParagraphs(article *Article, paragraphIDs []int, editorID int) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = snapshotArticle(tx, article.ID, editorID)
+	if err != nil {
+		return err
+	}
+
+	existing, err := loadParagraphIDs(tx, article.ID)
+	if err != nil {
+		return err
+	}
+
+	sorted := slices.Clone(paragraphIDs)
+	slices.Sort(sorted)
+	slices.Sort(existing)
+	if !slices.Equal(sorted, existing) {
+		return ErrParagraphMismatch
+	}
+
+	err = touchArticle(tx, article)
+	if err != nil {
+		return err
+	}
+
+	for i, id := range paragraphIDs {
+		_, err := tx.Exec(`UPDATE paragraphs SET order_index = $1 WHERE id = $2 AND article_id = $3`, i+1, id, article.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"fmt"
+	"math"
+)
+
+// ReviewFilter pages and orders the reviews of one article.
+type ReviewFilter struct {
+	Sort     string
+	Page     int
+	PageSize int
+}
+
+// reviewSortSafelist maps the sort values clients may send to ORDER BY
+// clauses. Ties are broken by recency so pages stay stable.
+var reviewSortSafelist = map[string]string{
+	"newest":  "r.created_at DESC, r.id DESC",
+	"highest": "r.rating DESC, r.created_at DESC, r.id DESC",
+	"lowest":  "r.rating ASC, r.created_at DESC, r.id DESC",
+	"helpful": "(SELECT count(*) FROM review_votes v WHERE v.review_id = r.id AND v.helpful) DESC, r.created_at DESC, r.id DESC",
+}
+
+func (f *ReviewFilter) Validate() error {
+	if f.Sort == "" {
+		f.Sort = "newest"
+	}
+	if f.PageSize == 0 {
+		f.PageSize = DefaultPageSize
+	}
+	if f.Page == 0 {
+		f.Page = 1
+	}
+
+	if _, ok := reviewSortSafelist[f.Sort]; !ok {
+		return fmt.Errorf("invalid sort value %q", f.Sort)
+	}
+
+	return ValidatePage(f.Page, f.PageSize)
+}
+
+// RatingSummary aggregates the ratings of an article. Histogram maps every
+// star value from 1 to 5 to the number of reviews that gave it.
+type RatingSummary struct {
+	Average   float64     `json:"average"`
+	Count     int         `json:"count"`
+	Histogram map[int]int `json:"histogram"`
+}
+
+func (pg *PostgresReviewStore) ListArticleReviews(articleID int64, filter ReviewFilter) ([]*Review, Metadata, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	query := `
+	SELECT count(*) OVER(), ` + reviewColumns + `
+	FROM reviews r
+	WHERE r.article_id = $1 AND r.deleted_at IS NULL AND r.hidden_at IS NULL
+	ORDER BY ` + reviewSortSafelist[filter.Sort] + `
+	LIMIT $2 OFFSET $3`
+
+	rows, err := pg.db.Query(query, articleID, filter.PageSize, (filter.Page-1)*filter.PageSize)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	reviews := []*Review{}
+	for rows.Next() {
+		review := &Review{}
+		err = rows.Scan(append([]any{&totalRecords}, review.scanDest()...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		reviews = append(reviews, review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return reviews, calculateMetadata(totalRecords, filter.Page, filter.PageSize), nil
+}
+
+func (pg *PostgresReviewStore) GetRatingSummary(articleID int64) (*RatingSummary, error) {
+	query := `
+	SELECT rating, count(*)
+	FROM reviews
+	WHERE article_id = $1 AND deleted_at IS NULL AND hidden_at IS NULL
+	GROUP BY rating`
+
+	rows, err := pg.db.Query(query, articleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[int]int{}
+	for rows.Next() {
+		var rating, count int
+		if err := rows.Scan(&rating, &count); err != nil {
+			return nil, err
+		}
+		counts[rating] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return newRatingSummary(counts), nil
+}
+
+// newRatingSummary builds the summary from the number of reviews per star
+// value. The average is rounded to two decimals.
+func newRatingSummary(counts map[int]int) *RatingSummary {
+	summary := &RatingSummary{Histogram: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+	total := 0
+	for rating, count := range counts {
+		summary.Histogram[rating] = count
+		summary.Count += count
+		total += rating * count
+	}
+
+	if summary.Count > 0 {
+		summary.Average = math.Round(float64(total)/float64(summary.Count)*100) / 100
+	}
+
+	return summary
+}
@@ -0,0 +1,19 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRatingSummary(t *testing.T) {
+	summary := newRatingSummary(map[int]int{5: 2, 4: 1})
+	assert.Equal(t, 3, summary.Count)
+	assert.Equal(t, 4.67, summary.Average)
+	assert.Equal(t, map[int]int{1: 0, 2: 0, 3: 0, 4: 1, 5: 2}, summary.Histogram)
+
+	empty := newRatingSummary(map[int]int{})
+	assert.Equal(t, 0, empty.Count)
+	assert.Equal(t, 0.0, empty.Average)
+	assert.Len(t, empty.Histogram, 5)
+}
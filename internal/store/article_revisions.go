@@ -0,0 +1,134 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// ArticleRevision is a snapshot of an article's content taken right before
+// an edit replaced it. EditedBy is the user whose edit triggered the
+// snapshot.
+type ArticleRevision struct {
+	ArticleID   int         `json:"article_id"`
+	Revision    int         `json:"revision"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Image       string      `json:"image"`
+	Paragraphs  []Paragraph `json:"paragraphs,omitempty"`
+	EditedBy    *int        `json:"edited_by"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// snapshotArticle copies the current content of the article into
+// article_revisions. It locks the article row so concurrent edits get
+// consecutive revision numbers, and must run inside the editing transaction
+// before anything is changed.
+func snapshotArticle(tx *sql.Tx, articleID int, editorID int) error {
+	var id int
+	err := tx.QueryRow(`SELECT id FROM articles WHERE id = $1 FOR UPDATE`, articleID).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	var editedBy *int
+	if editorID != 0 {
+		editedBy = &editorID
+	}
+
+	query := `
+	INSERT INTO article_revisions (article_id, revision, title, description, image, paragraphs, edited_by)
+	SELECT a.id,
+		COALESCE((SELECT MAX(revision) FROM article_revisions WHERE article_id = a.id), 0) + 1,
+		a.title, a.description, a.image,
+		COALESCE((
+			SELECT jsonb_agg(jsonb_build_object(
+				'id', p.id,
+				'headline', p.headline,
+				'body', p.body,
+				'order_index', p.order_index,
+				'created_at', p.created_at,
+				'updated_at', p.updated_at
+			) ORDER BY p.order_index)
+			FROM paragraphs p
+			WHERE p.article_id = a.id
+		), '[]'::jsonb),
+		$2
+	FROM articles a
+	WHERE a.id = $1`
+
+	_, err = tx.Exec(query, articleID, editedBy)
+	return err
+}
+
+func (pg *PostgresArticleStore) ListRevisions(articleID int64) ([]*ArticleRevision, error) {
+	query := `
+	SELECT article_id, revision, title, description, image, edited_by, created_at
+	FROM article_revisions
+	WHERE article_id = $1
+	ORDER BY revision DESC`
+
+	rows, err := pg.db.Query(query, articleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*ArticleRevision{}
+	for rows.Next() {
+		revision := &ArticleRevision{}
+		var description, image sql.NullString
+		err = rows.Scan(
+			&revision.ArticleID,
+			&revision.Revision,
+			&revision.Title,
+			&description,
+			&image,
+			&revision.EditedBy,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		revision.Description = description.String
+		revision.Image = image.String
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (pg *PostgresArticleStore) GetRevision(articleID int64, revisionNumber int) (*ArticleRevision, error) {
+	query := `
+	SELECT article_id, revision, title, description, image, paragraphs, edited_by, created_at
+	FROM article_revisions
+	WHERE article_id = $1 AND revision = $2`
+
+	revision := &ArticleRevision{}
+	var description, image sql.NullString
+	var paragraphs []byte
+	err := pg.db.QueryRow(query, articleID, revisionNumber).Scan(
+		&revision.ArticleID,
+		&revision.Revision,
+		&revision.Title,
+		&description,
+		&image,
+		&paragraphs,
+		&revision.EditedBy,
+		&revision.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	revision.Description = description.String
+	revision.Image = image.String
+	if err := json.Unmarshal(paragraphs, &revision.Paragraphs); err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
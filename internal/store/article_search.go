@@ -0,0 +1,103 @@
+package store
+
+import (
+	"errors"
+	"html"
+	"strings"
+)
+
+// ArticleSearchResult is an article matched by a full-text search. Snippet
+// is taken from the best matching paragraph (or the description when only
+// the title/description matched) with the hits wrapped in <mark> tags. The
+// rest of the snippet is HTML-escaped, so it is safe to render as HTML.
+type ArticleSearchResult struct {
+	Article
+	Rank        float64 `json:"rank"`
+	ParagraphID *int    `json:"paragraph_id"`
+	Snippet     string  `json:"snippet"`
+}
+
+// ts_headline marks hits with control characters instead of the final tags,
+// so the author's text can be escaped before the tags are added.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+const headlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", MaxWords=35, MinWords=15, MaxFragments=2"
+
+// highlightSnippet turns a ts_headline result into HTML: the text is
+// escaped and the hit markers become <mark> tags.
+func highlightSnippet(snippet string) string {
+	return strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>").Replace(html.EscapeString(snippet))
+}
+
+func (pg *PostgresArticleStore) SearchArticles(q string, page, pageSize int) ([]*ArticleSearchResult, Metadata, error) {
+	if q == "" {
+		return nil, Metadata{}, errors.New("search query is required")
+	}
+
+	if err := ValidatePage(page, pageSize); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	query := `
+	WITH q AS (
+		SELECT websearch_to_tsquery('english', $1) AS query
+	),
+	matches AS (
+		SELECT a.id AS article_id, ts_rank(a.search, q.query) AS rank
+		FROM articles a, q
+		WHERE a.search @@ q.query
+		UNION ALL
+		SELECT p.article_id, ts_rank(p.search, q.query)
+		FROM paragraphs p, q
+		WHERE p.search @@ q.query
+	),
+	ranked AS (
+		SELECT article_id, SUM(rank) AS rank
+		FROM matches
+		GROUP BY article_id
+	)
+	SELECT count(*) OVER(), `+articleColumns+`,
+		r.rank, best.id,
+		COALESCE(best.snippet, ts_headline('english', translate(coalesce(a.description, ''), $5, ''), q.query, $4))
+	FROM ranked r
+	JOIN articles a ON a.id = r.article_id AND a.status = 'published' AND a.deleted_at IS NULL AND a.hidden_at IS NULL
+	CROSS JOIN q
+	LEFT JOIN LATERAL (
+		SELECT p.id, ts_headline('english', translate(p.headline || '. ' || coalesce(p.body, ''), $5, ''), q.query, $4) AS snippet
+		FROM paragraphs p
+		WHERE p.article_id = a.id AND p.search @@ q.query
+		ORDER BY ts_rank(p.search, q.query) DESC, p.order_index
+		LIMIT 1
+	) best ON true
+	ORDER BY r.rank DESC, a.id DESC
+	LIMIT $2 OFFSET $3`
+
+	rows, err := pg.db.Query(query, q, pageSize, (page-1)*pageSize, headlineOptions, highlightStart+highlightStop)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	results := []*ArticleSearchResult{}
+	for rows.Next() {
+		result := &ArticleSearchResult{}
+		dest := append([]any{&totalRecords}, result.scanDest()...)
+		dest = append(dest, &result.Rank, &result.ParagraphID, &result.Snippet)
+		err = rows.Scan(dest...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		result.Snippet = highlightSnippet(result.Snippet)
+		results = append(results, result)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	return results, calculateMetadata(totalRecords, page, pageSize), nil
+}
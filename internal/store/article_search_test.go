@@ -0,0 +1,22 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlightSnippet(t *testing.T) {
+	snippet := "a <script>alert(1)</script> " + highlightStart + "golang" + highlightStop + " & more"
+	assert.Equal(t, "a &lt;script&gt;alert(1)&lt;/script&gt; <mark>golang</mark> &amp; more", highlightSnippet(snippet))
+}
+
+func TestSearchArticlesRejectsPageOutOfRange(t *testing.T) {
+	pg := &PostgresArticleStore{}
+
+	_, _, err := pg.SearchArticles("golang", 20_000_000, DefaultPageSize)
+	assert.EqualError(t, err, "page must be between 1 and 10000000")
+
+	_, _, err = pg.SearchArticles("golang", 1, MaxPageSize+1)
+	assert.Error(t, err)
+}
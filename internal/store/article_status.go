@@ -0,0 +1,112 @@
+package store
+
+import (
+	"database/sql"
+	"slices"
+	"time"
+)
+
+const (
+	ArticleStatusDraft     = "draft"
+	ArticleStatusInReview  = "in_review"
+	ArticleStatusScheduled = "scheduled"
+	ArticleStatusPublished = "published"
+	ArticleStatusArchived  = "archived"
+)
+
+// articleTransitions lists, for every status, the statuses an article may
+// move to next.
+var articleTransitions = map[string][]string{
+	ArticleStatusDraft:     {ArticleStatusInReview, ArticleStatusScheduled, ArticleStatusPublished, ArticleStatusArchived},
+	ArticleStatusInReview:  {ArticleStatusDraft, ArticleStatusScheduled, ArticleStatusPublished, ArticleStatusArchived},
+	ArticleStatusScheduled: {ArticleStatusDraft, ArticleStatusScheduled, ArticleStatusPublished, ArticleStatusArchived},
+	ArticleStatusPublished: {ArticleStatusDraft, ArticleStatusArchived},
+	ArticleStatusArchived:  {ArticleStatusDraft, ArticleStatusPublished},
+}
+
+func IsValidArticleStatus(status string) bool {
+	_, ok := articleTransitions[status]
+	return ok
+}
+
+func (a *Article) IsPublished() bool {
+	return a.Status == ArticleStatusPublished
+}
+
+// IsPublic reports whether anyone may read the article: it is published
+// and moderation has not hidden it.
+func (a *Article) IsPublic() bool {
+	return a.IsPublished() && a.HiddenAt == nil
+}
+
+func (a *Article) CanTransitionTo(status string) bool {
+	return slices.Contains(articleTransitions[a.Status], status)
+}
+
+// TransitionTo moves the article to status, keeping PublishedAt and
+// PublishAt in step: publishing stamps the current time and drops any
+// schedule, going back to draft clears both. Scheduling expects PublishAt
+// to be set by the caller.
+func (a *Article) TransitionTo(status string) {
+	switch status {
+	case ArticleStatusPublished:
+		now := time.Now()
+		a.PublishedAt = &now
+		a.PublishAt = nil
+	case ArticleStatusDraft, ArticleStatusInReview:
+		a.PublishedAt = nil
+		a.PublishAt = nil
+	case ArticleStatusScheduled:
+		a.PublishedAt = nil
+	}
+	a.Status = status
+}
+
+func (pg *PostgresArticleStore) UpdateArticleStatus(article *Article) error {
+	query := `
+	UPDATE articles
+	SET status = $1, published_at = $2, publish_at = $3, updated_at = NOW(), version = version + 1
+	WHERE id = $4 AND version = $5 AND deleted_at IS NULL
+	RETURNING version, updated_at`
+
+	err := pg.db.QueryRow(query, article.Status, article.PublishedAt, article.PublishAt, article.ID, article.Version).Scan(&article.Version, &article.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrEditConflict
+	}
+	return err
+}
+
+// PublishDueArticles publishes up to limit scheduled articles whose
+// publish_at has passed and returns their ids. Rows are claimed with
+// FOR UPDATE SKIP LOCKED, so several server instances can poll at the same
+// time without publishing an article twice or blocking each other.
+func (pg *PostgresArticleStore) PublishDueArticles(now time.Time, limit int) ([]int, error) {
+	query := `
+	UPDATE articles
+	SET status = 'published', published_at = publish_at, publish_at = NULL, updated_at = NOW(), version = version + 1
+	WHERE status = 'scheduled' AND id IN (
+		SELECT id FROM articles
+		WHERE status = 'scheduled' AND publish_at <= $1 AND deleted_at IS NULL
+		ORDER BY publish_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id`
+
+	rows, err := pg.db.Query(query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -2,20 +2,61 @@ package store
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
 type Article struct {
 	ID          int         `json:"id"`
 	Title       string      `json:"title"`
+	Slug        string      `json:"slug"`
 	Description string      `json:"description"`
 	Image       string      `json:"image"`
 	AuthorId    int         `json:"author_id"`
+	CategoryID  *int        `json:"category_id"`
+	Status      string      `json:"status"`
+	PublishedAt *time.Time  `json:"published_at"`
+	PublishAt   *time.Time  `json:"publish_at"`
+	HiddenAt    *time.Time  `json:"hidden_at,omitempty"`
+	Tags        []string    `json:"tags"`
 	Paragraphs  []Paragraph `json:"paragraphs"`
+	Version     int         `json:"version"`
 	CreatedAt   time.Time    `json:"created_at"`
 	UpdatedAt   time.Time    `json:"updated_at"`
 }
 
+// articleColumns is the column list every article query selects, in the
+// order scanDest expects them.
+const articleColumns = `a.id, a.title, a.slug, a.description, a.image, a.author_id, a.category_id, a.status, a.published_at, a.publish_at, a.hidden_at, a.version, a.created_at, a.updated_at,
+	ARRAY(
+		SELECT t.name FROM articles_tags art
+		INNER JOIN tags t ON t.id = art.tag_id
+		WHERE art.article_id = a.id
+		ORDER BY t.name
+	)`
+
+func (a *Article) scanDest() []any {
+	return []any{
+		&a.ID,
+		&a.Title,
+		&a.Slug,
+		&a.Description,
+		&a.Image,
+		&a.AuthorId,
+		&a.CategoryID,
+		&a.Status,
+		&a.PublishedAt,
+		&a.PublishAt,
+		&a.HiddenAt,
+		&a.Version,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+		(*textArray)(&a.Tags),
+	}
+}
+
 type Paragraph struct {
 	ID         int    `json:"id"`
 	Headline   string `json:"headline"`
@@ -36,8 +77,157 @@ func NewPostgresArticleStore(db *sql.DB) *PostgresArticleStore {
 type ArticleStore interface {
 	CreateArticle(*Article) (*Article, error)
 	GetArticleById(id int64) (*Article, error)
-	UpdateArticle(*Article) error
-	DeleteArticle(id int64) error
+	GetArticleBySlug(slug string) (*Article, error)
+	GetDeletedArticleById(id int64) (*Article, error)
+	GetArticleByOldSlug(oldSlug string) (*Article, error)
+	UpdateArticle(article *Article, editorID int) error
+	DeleteArticle(id int64, version int) error
+	RestoreArticle(id int64) error
+	ListArticles(filter ArticleFilter) ([]*Article, Metadata, error)
+	SearchArticles(query string, page, pageSize int) ([]*ArticleSearchResult, Metadata, error)
+	UpdateArticleStatus(*Article) error
+	PublishDueArticles(now time.Time, limit int) ([]int, error)
+	CreateParagraph(article *Article, paragraph *Paragraph, editorID int) error
+	UpdateParagraph(article *Article, paragraph *Paragraph, editorID int) error
+	DeleteParagraph(article *Article, paragraphID int, editorID int) error
+	ReorderParagraphs(article *Article, paragraphIDs []int, editorID int) error
+	ListRevisions(articleID int64) ([]*ArticleRevision, error)
+	GetRevision(articleID int64, revision int) (*ArticleRevision, error)
+}
+
+// ArticleFilter narrows down and orders the result of ListArticles. When
+// Cursor is set the listing is keyset paginated and Page is ignored.
+type ArticleFilter struct {
+	AuthorID      *int
+	Status        string
+	Tag           string
+	CategoryID    *int
+	// IncludeDescendants widens the CategoryID filter to every category
+	// below it as well.
+	IncludeDescendants bool
+	// ViewerID is the user asking for the listing (0 for anonymous). Unless
+	// IncludeAll is set, only published articles and the viewer's own
+	// articles are returned.
+	ViewerID      int
+	IncludeAll    bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	Sort          string
+	Page          int
+	PageSize      int
+	Cursor        string
+}
+
+type articleSort struct {
+	column string
+	desc   bool
+}
+
+var articleSortSafelist = map[string]articleSort{
+	"created_at":  {column: "created_at"},
+	"-created_at": {column: "created_at", desc: true},
+	"updated_at":  {column: "updated_at"},
+	"-updated_at": {column: "updated_at", desc: true},
+	"title":       {column: "title"},
+	"-title":      {column: "title", desc: true},
+}
+
+func (f *ArticleFilter) Validate() error {
+	if f.Sort == "" {
+		f.Sort = "-created_at"
+	}
+	if f.PageSize == 0 {
+		f.PageSize = DefaultPageSize
+	}
+	if f.Page == 0 {
+		f.Page = 1
+	}
+
+	if f.Status != "" && !IsValidArticleStatus(f.Status) {
+		return fmt.Errorf("invalid status value %q", f.Status)
+	}
+
+	if _, ok := articleSortSafelist[f.Sort]; !ok {
+		return fmt.Errorf("invalid sort value %q", f.Sort)
+	}
+
+	if f.CreatedAfter != nil && f.CreatedBefore != nil && f.CreatedAfter.After(*f.CreatedBefore) {
+		return errors.New("created_after must be before created_before")
+	}
+
+	if f.UpdatedAfter != nil && f.UpdatedBefore != nil && f.UpdatedAfter.After(*f.UpdatedBefore) {
+		return errors.New("updated_after must be before updated_before")
+	}
+
+	return ValidatePage(f.Page, f.PageSize)
+}
+
+func (f *ArticleFilter) conditions() ([]string, []any) {
+	var conditions []string
+	var args []any
+
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	conditions = append(conditions, "a.deleted_at IS NULL")
+
+	if !f.IncludeAll {
+		if f.ViewerID != 0 {
+			add("((a.status = 'published' AND a.hidden_at IS NULL) OR a.author_id = $%d)", f.ViewerID)
+		} else {
+			conditions = append(conditions, "a.status = 'published' AND a.hidden_at IS NULL")
+		}
+	}
+	if f.Status != "" {
+		add("a.status = $%d", f.Status)
+	}
+	if f.AuthorID != nil {
+		add("a.author_id = $%d", *f.AuthorID)
+	}
+	if f.Tag != "" {
+		add(`EXISTS (
+			SELECT 1 FROM articles_tags art
+			INNER JOIN tags t ON t.id = art.tag_id
+			WHERE art.article_id = a.id AND t.name = $%d)`, f.Tag)
+	}
+	if f.CategoryID != nil {
+		if f.IncludeDescendants {
+			add(`a.category_id IN (
+				WITH RECURSIVE tree AS (
+					SELECT id FROM categories WHERE id = $%d
+					UNION ALL
+					SELECT c.id FROM categories c INNER JOIN tree ON c.parent_id = tree.id
+				)
+				SELECT id FROM tree)`, *f.CategoryID)
+		} else {
+			add("a.category_id = $%d", *f.CategoryID)
+		}
+	}
+	if f.CreatedAfter != nil {
+		add("a.created_at >= $%d", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		add("a.created_at < $%d", *f.CreatedBefore)
+	}
+	if f.UpdatedAfter != nil {
+		add("a.updated_at >= $%d", *f.UpdatedAfter)
+	}
+	if f.UpdatedBefore != nil {
+		add("a.updated_at < $%d", *f.UpdatedBefore)
+	}
+
+	return conditions, args
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
 }
 
 func (pg *PostgresArticleStore) CreateArticle(article *Article) (*Article, error) {
@@ -48,12 +238,24 @@ func (pg *PostgresArticleStore) CreateArticle(article *Article) (*Article, error
 
 	defer tx.Rollback()
 
-	query :=
-		`INSERT INTO articles (title,description,image,author_id)
-	VALUES($1, $2, $3, $4)
-	RETURNING id`
+	if article.Status == "" {
+		article.Status = ArticleStatusDraft
+	}
+
+	err = retrySlugConflict(tx, func() error {
+		slug, err := uniqueSlug(tx, Slugify(article.Title), 0)
+		if err != nil {
+			return err
+		}
+
+		query :=
+			`INSERT INTO articles (title,slug,description,image,author_id,category_id,status,published_at,publish_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, version, created_at, updated_at`
 
-	err = tx.QueryRow(query, article.Title, article.Description, article.Image, article.AuthorId).Scan(&article.ID)
+		article.Slug = slug
+		return tx.QueryRow(query, article.Title, article.Slug, article.Description, article.Image, article.AuthorId, article.CategoryID, article.Status, article.PublishedAt, article.PublishAt).Scan(&article.ID, &article.Version, &article.CreatedAt, &article.UpdatedAt)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -70,9 +272,15 @@ func (pg *PostgresArticleStore) CreateArticle(article *Article) (*Article, error
 			article.Paragraphs[i].Body,
 			article.Paragraphs[i].OrderIndex,
 		).Scan(&article.Paragraphs[i].ID)
+		if err != nil {
+			return nil, err
+		}
 	}
-	
 
+	err = setArticleTags(tx, article.ID, article.Tags)
+	if err != nil {
+		return nil, err
+	}
 
 	err = tx.Commit()
 	if err != nil {
@@ -82,12 +290,27 @@ func (pg *PostgresArticleStore) CreateArticle(article *Article) (*Article, error
 }
 
 func (pg *PostgresArticleStore) GetArticleById(id int64) (*Article, error) {
+	return pg.getArticle("a.id = $1 AND a.deleted_at IS NULL", id)
+}
+
+// GetArticleBySlug finds an article by its current slug only; old slugs are
+// resolved with GetArticleByOldSlug.
+func (pg *PostgresArticleStore) GetArticleBySlug(slug string) (*Article, error) {
+	return pg.getArticle("a.slug = $1 AND a.deleted_at IS NULL", slug)
+}
+
+// GetDeletedArticleById finds an article that is in the trash.
+func (pg *PostgresArticleStore) GetDeletedArticleById(id int64) (*Article, error) {
+	return pg.getArticle("a.id = $1 AND a.deleted_at IS NOT NULL", id)
+}
+
+func (pg *PostgresArticleStore) getArticle(condition string, arg any) (*Article, error) {
 	article := &Article{}
 	query := `
-	SELECT id, title, description,image, author_id,created_at, updated_at
-	FROM articles WHERE id = $1`
+	SELECT ` + articleColumns + `
+	FROM articles a WHERE ` + condition
 
-	err := pg.db.QueryRow(query, id).Scan(&article.ID, &article.Title, &article.Description, &article.Image, &article.AuthorId, &article.CreatedAt, &article.UpdatedAt)
+	err := pg.db.QueryRow(query, arg).Scan(article.scanDest()...)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -102,7 +325,7 @@ func (pg *PostgresArticleStore) GetArticleById(id int64) (*Article, error) {
 	WHERE article_id = $1
 	ORDER BY order_index`
 
-	rows, err := pg.db.Query(paragraphQuery, id)
+	rows, err := pg.db.Query(paragraphQuery, article.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -128,73 +351,175 @@ func (pg *PostgresArticleStore) GetArticleById(id int64) (*Article, error) {
 	return article, nil
 }
 
-func (pg *PostgresArticleStore) UpdateArticle(article *Article) error {
+// UpdateArticle saves the article and its paragraphs. The content it
+// replaces is kept as a new revision attributed to editorID. It fails with
+// ErrEditConflict if article.Version is no longer the stored version.
+func (pg *PostgresArticleStore) UpdateArticle(article *Article, editorID int) error {
 	tx, err := pg.db.Begin()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	query := `
-	UPDATE articles
-	SET title = $1, description = $2, image = $3, author_id = $4,updated_at = NOW()
-	WHERE id = $5`
-
-	result, err := tx.Exec(query, article.Title, article.Description, article.Image, article.AuthorId, article.ID)
+	err = snapshotArticle(tx, article.ID, editorID)
 	if err != nil {
 		return err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	err = renameSlug(tx, article)
 	if err != nil {
 		return err
 	}
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+
+	query := `
+	UPDATE articles
+	SET title = $1, description = $2, image = $3, author_id = $4, category_id = $5, updated_at = NOW(), version = version + 1
+	WHERE id = $6 AND version = $7 AND deleted_at IS NULL
+	RETURNING version, updated_at`
+
+	err = tx.QueryRow(query, article.Title, article.Description, article.Image, article.AuthorId, article.CategoryID, article.ID, article.Version).Scan(&article.Version, &article.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrEditConflict
+	}
+	if err != nil {
+		return err
 	}
 
-	_, err = tx.Exec(`DELETE FROM paragraphs WHERE article_id = $1`, article.ID)
+	err = syncParagraphs(tx, article)
 	if err != nil {
 		return err
 	}
 
-	for _, paragraph := range article.Paragraphs {
-		query := `
-		INSERT INTO paragraphs (article_id, headline, body, order_index)
-		VALUES($1, $2, $3, $4);
-		`
-		_, err := tx.Exec(query,
-			article.ID,
-			paragraph.Headline,
-			paragraph.Body,
-			paragraph.OrderIndex,
-		)
-		if err != nil {
-			return err
-		}
+	err = setArticleTags(tx, article.ID, article.Tags)
+	if err != nil {
+		return err
 	}
 
 	return tx.Commit()
 }
 
-func (pg *PostgresArticleStore) DeleteArticle(id int64) error {
+// DeleteArticle moves the article to the trash if it is still at version,
+// and fails with ErrEditConflict otherwise. It stays there, restorable,
+// until the purge job removes it for good.
+func (pg *PostgresArticleStore) DeleteArticle(id int64, version int) error {
 	query := `
-	DELETE from articles
-	WHERE id = $1`
+	UPDATE articles
+	SET deleted_at = NOW()
+	WHERE id = $1 AND version = $2 AND deleted_at IS NULL`
 
-	result, err := pg.db.Exec(query, id)
+	err := execOne(pg.db, query, id, version)
+	if err == sql.ErrNoRows {
+		return ErrEditConflict
+	}
+	return err
+}
+
+func (pg *PostgresArticleStore) RestoreArticle(id int64) error {
+	query := `
+	UPDATE articles
+	SET deleted_at = NULL
+	WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	return execOne(pg.db, query, id)
+}
+
+func (pg *PostgresArticleStore) ListArticles(filter ArticleFilter) ([]*Article, Metadata, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	sort := articleSortSafelist[filter.Sort]
+	direction, comparison := "ASC", ">"
+	if sort.desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	conditions, args := filter.conditions()
+
+	var totalRecords int
+	countQuery := `SELECT count(*) FROM articles a ` + whereClause(conditions)
+	err := pg.db.QueryRow(countQuery, args...).Scan(&totalRecords)
 	if err != nil {
-		return err
+		return nil, Metadata{}, err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	if filter.Cursor != "" {
+		c, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		cast := "timestamptz"
+		if sort.column == "title" {
+			cast = "text"
+		}
+		args = append(args, c.Value, c.ID)
+		conditions = append(conditions, fmt.Sprintf("(a.%s, a.id) %s ($%d::%s, $%d)",
+			sort.column, comparison, len(args)-1, cast, len(args)))
+	}
+
+	// one extra row tells us whether there is a next page for the cursor
+	limit := filter.PageSize + 1
+	offset := 0
+	if filter.Cursor == "" {
+		offset = (filter.Page - 1) * filter.PageSize
+	}
+	args = append(args, limit, offset)
+
+	query := fmt.Sprintf(`
+	SELECT `+articleColumns+`
+	FROM articles a
+	%s
+	ORDER BY a.%s %s, a.id %s
+	LIMIT $%d OFFSET $%d`,
+		whereClause(conditions), sort.column, direction, direction, len(args)-1, len(args))
+
+	rows, err := pg.db.Query(query, args...)
 	if err != nil {
-		return err
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	articles := []*Article{}
+	for rows.Next() {
+		article := &Article{}
+		err = rows.Scan(article.scanDest()...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		articles = append(articles, article)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
 	}
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	hasMore := len(articles) > filter.PageSize
+	if hasMore {
+		articles = articles[:filter.PageSize]
 	}
 
-	return nil
+	metadata := calculateMetadata(totalRecords, filter.Page, filter.PageSize)
+	if filter.Cursor != "" {
+		metadata = Metadata{PageSize: filter.PageSize, TotalRecords: totalRecords}
+	}
+
+	if hasMore {
+		metadata.NextCursor = articleCursor(articles[len(articles)-1], sort.column)
+	}
+
+	return articles, metadata, nil
+}
+
+func articleCursor(article *Article, column string) string {
+	c := cursor{ID: article.ID}
+	switch column {
+	case "title":
+		c.Value = article.Title
+	case "updated_at":
+		c.Value = article.UpdatedAt.Format(time.RFC3339Nano)
+	default:
+		c.Value = article.CreatedAt.Format(time.RFC3339Nano)
+	}
+	return encodeCursor(c)
 }
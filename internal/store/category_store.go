@@ -0,0 +1,173 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	ErrDuplicateCategory = errors.New("a category with this name already exists under the same parent")
+	ErrCategoryCycle     = errors.New("a category cannot be moved under itself or one of its subcategories")
+	ErrCategoryNotEmpty  = errors.New("category still has subcategories")
+)
+
+type Category struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	ParentID  *int      `json:"parent_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type PostgresCategoryStore struct {
+	db *sql.DB
+}
+
+func NewPostgresCategoryStore(db *sql.DB) *PostgresCategoryStore {
+	return &PostgresCategoryStore{db: db}
+}
+
+type CategoryStore interface {
+	CreateCategory(*Category) (*Category, error)
+	GetCategoryById(id int64) (*Category, error)
+	ListCategories() ([]*Category, error)
+	UpdateCategory(*Category) error
+	DeleteCategory(id int64) error
+}
+
+func (pg *PostgresCategoryStore) CreateCategory(category *Category) (*Category, error) {
+	query := `
+	INSERT INTO categories (name, parent_id)
+	VALUES ($1, $2)
+	RETURNING id, created_at, updated_at`
+
+	err := pg.db.QueryRow(query, category.Name, category.ParentID).Scan(&category.ID, &category.CreatedAt, &category.UpdatedAt)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicateCategory
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (pg *PostgresCategoryStore) GetCategoryById(id int64) (*Category, error) {
+	category := &Category{}
+	query := `
+	SELECT id, name, parent_id, created_at, updated_at
+	FROM categories
+	WHERE id = $1`
+
+	err := pg.db.QueryRow(query, id).Scan(&category.ID, &category.Name, &category.ParentID, &category.CreatedAt, &category.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// ListCategories returns every category, parents before their children and
+// siblings sorted by name.
+func (pg *PostgresCategoryStore) ListCategories() ([]*Category, error) {
+	query := `
+	WITH RECURSIVE tree AS (
+		SELECT id, ARRAY[lower(name)]::text[] AS path
+		FROM categories
+		WHERE parent_id IS NULL
+		UNION ALL
+		SELECT c.id, tree.path || lower(c.name)
+		FROM categories c
+		INNER JOIN tree ON c.parent_id = tree.id
+	)
+	SELECT c.id, c.name, c.parent_id, c.created_at, c.updated_at
+	FROM categories c
+	INNER JOIN tree ON tree.id = c.id
+	ORDER BY tree.path`
+
+	rows, err := pg.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+	for rows.Next() {
+		category := &Category{}
+		err = rows.Scan(&category.ID, &category.Name, &category.ParentID, &category.CreatedAt, &category.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+// UpdateCategory renames or moves the category. Moving it under itself or
+// one of its own descendants fails with ErrCategoryCycle.
+func (pg *PostgresCategoryStore) UpdateCategory(category *Category) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if category.ParentID != nil {
+		// serialize moves so two concurrent ones can't build a cycle together
+		_, err = tx.Exec(`LOCK TABLE categories IN SHARE ROW EXCLUSIVE MODE`)
+		if err != nil {
+			return err
+		}
+
+		var cycle bool
+		query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.parent_id
+			FROM categories c
+			INNER JOIN ancestors ON c.id = ancestors.parent_id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)`
+
+		err = tx.QueryRow(query, *category.ParentID, category.ID).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrCategoryCycle
+		}
+	}
+
+	query := `
+	UPDATE categories
+	SET name = $1, parent_id = $2, updated_at = NOW()
+	WHERE id = $3
+	RETURNING updated_at`
+
+	err = tx.QueryRow(query, category.Name, category.ParentID, category.ID).Scan(&category.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicateCategory
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteCategory removes a category without subcategories. Its articles are
+// left without a category. The parent_id foreign key refuses to delete a
+// category that still has subcategories, which is ErrCategoryNotEmpty.
+func (pg *PostgresCategoryStore) DeleteCategory(id int64) error {
+	err := execOne(pg.db, `DELETE FROM categories WHERE id = $1`, id)
+	if isForeignKeyViolation(err) {
+		return ErrCategoryNotEmpty
+	}
+	return err
+}
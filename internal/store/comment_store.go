@@ -0,0 +1,231 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// CommentEditWindow is how long after posting a comment its author can
+// still edit it.
+const CommentEditWindow = 15 * time.Minute
+
+// ErrCommentNotEditable is returned by UpdateComment when the comment was
+// deleted or its edit window closed before the update ran.
+var ErrCommentNotEditable = errors.New("comment can no longer be edited")
+
+// Comment is a comment on an article or, when ParentID is set, a reply to
+// another comment. A deleted comment that still has replies stays in the
+// thread as a tombstone: Deleted is set and Body and UserID are cleared.
+type Comment struct {
+	ID        int        `json:"id"`
+	ArticleID int        `json:"article_id"`
+	ParentID  *int       `json:"parent_id"`
+	UserID    *int       `json:"user_id"`
+	Body      string     `json:"body"`
+	Deleted   bool       `json:"deleted"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Replies   []*Comment `json:"replies"`
+}
+
+// CanEdit reports whether the comment is still inside its edit window.
+func (c *Comment) CanEdit(now time.Time) bool {
+	return !c.Deleted && now.Sub(c.CreatedAt) <= CommentEditWindow
+}
+
+const commentColumns = `c.id, c.article_id, c.parent_id, c.user_id, c.body, c.deleted_at IS NOT NULL, c.created_at, c.updated_at`
+
+func (c *Comment) scanDest() []any {
+	return []any{
+		&c.ID,
+		&c.ArticleID,
+		&c.ParentID,
+		&c.UserID,
+		&c.Body,
+		&c.Deleted,
+		&c.CreatedAt,
+		&c.UpdatedAt,
+	}
+}
+
+type PostgresCommentStore struct {
+	db *sql.DB
+}
+
+func NewPostgresCommentStore(db *sql.DB) *PostgresCommentStore {
+	return &PostgresCommentStore{db: db}
+}
+
+type CommentStore interface {
+	CreateComment(*Comment) (*Comment, error)
+	GetCommentById(id int64) (*Comment, error)
+	ListArticleComments(articleID int64) ([]*Comment, error)
+	UpdateComment(*Comment) error
+	DeleteComment(id int64) error
+}
+
+func (pg *PostgresCommentStore) CreateComment(comment *Comment) (*Comment, error) {
+	query := `
+	INSERT INTO comments (article_id, parent_id, user_id, body)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at, updated_at`
+
+	err := pg.db.QueryRow(query, comment.ArticleID, comment.ParentID, comment.UserID, comment.Body).Scan(&comment.ID, &comment.CreatedAt, &comment.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+func (pg *PostgresCommentStore) GetCommentById(id int64) (*Comment, error) {
+	comment := &Comment{}
+	query := `
+	SELECT ` + commentColumns + `
+	FROM comments c
+	WHERE c.id = $1`
+
+	err := pg.db.QueryRow(query, id).Scan(comment.scanDest()...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// ListArticleComments returns every comment of the article as a forest of
+// threads, oldest first at every level.
+func (pg *PostgresCommentStore) ListArticleComments(articleID int64) ([]*Comment, error) {
+	query := `
+	SELECT ` + commentColumns + `
+	FROM comments c
+	WHERE c.article_id = $1
+	ORDER BY c.created_at, c.id`
+
+	rows, err := pg.db.Query(query, articleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*Comment
+	for rows.Next() {
+		comment := &Comment{}
+		if err := rows.Scan(comment.scanDest()...); err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return BuildCommentTree(comments), nil
+}
+
+// BuildCommentTree nests the comments under their parents and returns the
+// top level ones. Sibling order follows the order of comments. A comment
+// whose parent is missing from the list is treated as top level.
+func BuildCommentTree(comments []*Comment) []*Comment {
+	byID := make(map[int]*Comment, len(comments))
+	for _, comment := range comments {
+		comment.Replies = []*Comment{}
+		byID[comment.ID] = comment
+	}
+
+	roots := []*Comment{}
+	for _, comment := range comments {
+		var parent *Comment
+		if comment.ParentID != nil {
+			parent = byID[*comment.ParentID]
+		}
+
+		if parent != nil {
+			parent.Replies = append(parent.Replies, comment)
+		} else {
+			roots = append(roots, comment)
+		}
+	}
+
+	return roots
+}
+
+// UpdateComment saves the new body. The edit window is checked again here
+// so an edit that was allowed when the comment was loaded can't land after
+// the window closed.
+func (pg *PostgresCommentStore) UpdateComment(comment *Comment) error {
+	query := `
+	UPDATE comments
+	SET body = $1, updated_at = NOW()
+	WHERE id = $2 AND deleted_at IS NULL AND created_at >= NOW() - make_interval(secs => $3)
+	RETURNING updated_at`
+
+	err := pg.db.QueryRow(query, comment.Body, comment.ID, CommentEditWindow.Seconds()).Scan(&comment.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrCommentNotEditable
+	}
+	return err
+}
+
+// DeleteComment removes the comment, or turns it into a tombstone when it
+// has replies. Removing a comment also removes the tombstones above it that
+// are left without any replies.
+func (pg *PostgresCommentStore) DeleteComment(id int64) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var parentID *int64
+	err = tx.QueryRow(`SELECT parent_id FROM comments WHERE id = $1 FOR UPDATE`, id).Scan(&parentID)
+	if err != nil {
+		return err
+	}
+
+	var hasReplies bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM comments WHERE parent_id = $1)`, id).Scan(&hasReplies)
+	if err != nil {
+		return err
+	}
+
+	if hasReplies {
+		query := `
+		UPDATE comments
+		SET body = '', user_id = NULL, deleted_at = NOW(), updated_at = NOW()
+		WHERE id = $1`
+
+		if _, err = tx.Exec(query, id); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	if _, err = tx.Exec(`DELETE FROM comments WHERE id = $1`, id); err != nil {
+		return err
+	}
+
+	// walk up while the parent is a tombstone nobody replies to anymore
+	for parentID != nil {
+		query := `
+		DELETE FROM comments p
+		WHERE p.id = $1 AND p.deleted_at IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM comments c WHERE c.parent_id = p.id)
+		RETURNING p.parent_id`
+
+		err = tx.QueryRow(query, *parentID).Scan(&parentID)
+		if err == sql.ErrNoRows {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCommentTree(t *testing.T) {
+	id := func(i int) *int { return &i }
+	comments := []*Comment{
+		{ID: 1},
+		{ID: 2, ParentID: id(1)},
+		{ID: 3},
+		{ID: 4, ParentID: id(2)},
+		{ID: 5, ParentID: id(1)},
+		{ID: 6, ParentID: id(99)},
+	}
+
+	roots := BuildCommentTree(comments)
+	require.Len(t, roots, 3)
+	assert.Equal(t, []int{1, 3, 6}, []int{roots[0].ID, roots[1].ID, roots[2].ID})
+
+	require.Len(t, roots[0].Replies, 2)
+	assert.Equal(t, 2, roots[0].Replies[0].ID)
+	assert.Equal(t, 5, roots[0].Replies[1].ID)
+	require.Len(t, roots[0].Replies[0].Replies, 1)
+	assert.Equal(t, 4, roots[0].Replies[0].Replies[0].ID)
+	assert.Empty(t, roots[1].Replies)
+}
+
+func TestCommentCanEdit(t *testing.T) {
+	now := time.Now()
+	assert.True(t, (&Comment{CreatedAt: now.Add(-time.Minute)}).CanEdit(now))
+	assert.False(t, (&Comment{CreatedAt: now.Add(-CommentEditWindow - time.Second)}).CanEdit(now))
+	assert.False(t, (&Comment{CreatedAt: now, Deleted: true}).CanEdit(now))
+}
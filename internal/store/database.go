@@ -2,13 +2,53 @@ package store
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"io/fs"
+
+	"github.com/jackc/pgconn"
 	_ "github.com/jackc/pgx/v4/stdlib"
 	"github.com/pressly/goose/v3"
 )
 
 
+// ErrEditConflict is returned by the Update methods when the row changed
+// since it was read, i.e. its version no longer matches.
+var ErrEditConflict = errors.New("edit conflict")
+
+// isUniqueViolation reports whether err comes from a unique constraint or
+// unique index rejecting a row.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// isForeignKeyViolation reports whether err comes from a foreign key
+// rejecting a change, e.g. deleting a row that is still referenced.
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+// execOne runs a statement that must affect a row and returns
+// sql.ErrNoRows when it affected none.
+func execOne(db *sql.DB, query string, args ...any) error {
+	result, err := db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
 
 func Open() (*sql.DB, error) {
 	db, err := sql.Open("pgx", "host=localhost user=postgres password=postgres dbname=articles port=5432 sslmode=disable")
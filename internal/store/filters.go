@@ -0,0 +1,81 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Metadata describes where a page sits inside the full result set. Offset
+// pagination fills in the page fields, cursor pagination fills in NextCursor.
+type Metadata struct {
+	CurrentPage  int    `json:"current_page,omitempty"`
+	PageSize     int    `json:"page_size"`
+	FirstPage    int    `json:"first_page,omitempty"`
+	LastPage     int    `json:"last_page,omitempty"`
+	TotalRecords int    `json:"total_records"`
+	NextCursor   string `json:"next_cursor,omitempty"`
+}
+
+func calculateMetadata(totalRecords, page, pageSize int) Metadata {
+	if totalRecords == 0 {
+		return Metadata{PageSize: pageSize}
+	}
+
+	return Metadata{
+		CurrentPage:  page,
+		PageSize:     pageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+		TotalRecords: totalRecords,
+	}
+}
+
+// ValidatePage checks offset pagination parameters. Its errors are meant
+// for the client.
+func ValidatePage(page, pageSize int) error {
+	if page < 1 || page > 10_000_000 {
+		return errors.New("page must be between 1 and 10000000")
+	}
+
+	if pageSize < 1 || pageSize > MaxPageSize {
+		return fmt.Errorf("page_size must be between 1 and %d", MaxPageSize)
+	}
+
+	return nil
+}
+
+// cursor is the position of the last row of a page: the value of the sort
+// column plus the id as a tie breaker.
+type cursor struct {
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func encodeCursor(c cursor) string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(js, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
@@ -0,0 +1,79 @@
+package store
+
+const (
+	DiffUnchanged = "unchanged"
+	DiffAdded     = "added"
+	DiffRemoved   = "removed"
+	DiffModified  = "modified"
+)
+
+// ParagraphChange is one step of a paragraph-level diff. From is nil for
+// added paragraphs and To is nil for removed ones.
+type ParagraphChange struct {
+	Op   string     `json:"op"`
+	From *Paragraph `json:"from,omitempty"`
+	To   *Paragraph `json:"to,omitempty"`
+}
+
+func sameParagraph(a, b Paragraph) bool {
+	return a.Headline == b.Headline && a.Body == b.Body
+}
+
+// DiffParagraphs compares two ordered lists of paragraphs by content. It
+// keeps the longest common subsequence as unchanged and, between two
+// unchanged runs, pairs up removed and added paragraphs as modified.
+func DiffParagraphs(from, to []Paragraph) []ParagraphChange {
+	// lcs[i][j] is the length of the LCS of from[i:] and to[j:]
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if sameParagraph(from[i], to[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	changes := []ParagraphChange{}
+	var removed, added []Paragraph
+
+	flush := func() {
+		n := min(len(removed), len(added))
+		for k := 0; k < n; k++ {
+			changes = append(changes, ParagraphChange{Op: DiffModified, From: &removed[k], To: &added[k]})
+		}
+		for k := n; k < len(removed); k++ {
+			changes = append(changes, ParagraphChange{Op: DiffRemoved, From: &removed[k]})
+		}
+		for k := n; k < len(added); k++ {
+			changes = append(changes, ParagraphChange{Op: DiffAdded, To: &added[k]})
+		}
+		removed, added = nil, nil
+	}
+
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case sameParagraph(from[i], to[j]):
+			flush()
+			changes = append(changes, ParagraphChange{Op: DiffUnchanged, From: &from[i], To: &to[j]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			removed = append(removed, from[i])
+			i++
+		default:
+			added = append(added, to[j])
+			j++
+		}
+	}
+	removed = append(removed, from[i:]...)
+	added = append(added, to[j:]...)
+	flush()
+
+	return changes
+}
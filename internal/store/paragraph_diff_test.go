@@ -0,0 +1,65 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffParagraphs(t *testing.T) {
+	intro := Paragraph{Headline: "Intro", Body: "Hello"}
+	setup := Paragraph{Headline: "Setup", Body: "Install Go"}
+	setupFixed := Paragraph{Headline: "Setup", Body: "Install Go 1.24"}
+	outro := Paragraph{Headline: "Outro", Body: "Bye"}
+	extra := Paragraph{Headline: "Extra", Body: "More"}
+
+	tests := []struct {
+		name string
+		from []Paragraph
+		to   []Paragraph
+		ops  []string
+	}{
+		{
+			name: "identical",
+			from: []Paragraph{intro, outro},
+			to:   []Paragraph{intro, outro},
+			ops:  []string{DiffUnchanged, DiffUnchanged},
+		},
+		{
+			name: "edited paragraph",
+			from: []Paragraph{intro, setup, outro},
+			to:   []Paragraph{intro, setupFixed, outro},
+			ops:  []string{DiffUnchanged, DiffModified, DiffUnchanged},
+		},
+		{
+			name: "added and removed",
+			from: []Paragraph{intro, setup},
+			to:   []Paragraph{intro, extra, outro},
+			ops:  []string{DiffUnchanged, DiffModified, DiffAdded},
+		},
+		{
+			name: "removed at the end",
+			from: []Paragraph{intro, outro},
+			to:   []Paragraph{intro},
+			ops:  []string{DiffUnchanged, DiffRemoved},
+		},
+		{
+			name: "from empty",
+			from: nil,
+			to:   []Paragraph{intro, outro},
+			ops:  []string{DiffAdded, DiffAdded},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := DiffParagraphs(tt.from, tt.to)
+
+			ops := make([]string, len(changes))
+			for i, change := range changes {
+				ops[i] = change.Op
+			}
+			assert.Equal(t, tt.ops, ops)
+		})
+	}
+}
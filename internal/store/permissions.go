@@ -0,0 +1,40 @@
+package store
+
+import "slices"
+
+const (
+	RoleReader    = "reader"
+	RoleAuthor    = "author"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+const (
+	PermissionArticlesWrite    = "articles:write"
+	PermissionArticlesManage   = "articles:manage"
+	PermissionReviewsWrite     = "reviews:write"
+	PermissionReviewsManage    = "reviews:manage"
+	PermissionUsersManage      = "users:manage"
+	PermissionCategoriesManage = "categories:manage"
+	PermissionCommentsWrite    = "comments:write"
+	PermissionCommentsManage   = "comments:manage"
+	PermissionReportsManage    = "reports:manage"
+)
+
+// Permissions holds the permission codes granted to a user through their role.
+type Permissions []string
+
+func (p Permissions) Include(code string) bool {
+	return slices.Contains(p, code)
+}
+
+// Intersect returns the permissions that are also in codes.
+func (p Permissions) Intersect(codes []string) Permissions {
+	permissions := Permissions{}
+	for _, code := range p {
+		if slices.Contains(codes, code) {
+			permissions = append(permissions, code)
+		}
+	}
+	return permissions
+}
@@ -0,0 +1,348 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgtype"
+)
+
+const (
+	ReportTargetArticle = "article"
+	ReportTargetReview  = "review"
+	ReportTargetUser    = "user"
+)
+
+const (
+	ReportStatusOpen     = "open"
+	ReportStatusResolved = "resolved"
+)
+
+const (
+	ReportActionDismiss     = "dismiss"
+	ReportActionHideContent = "hide_content"
+	ReportActionSuspendUser = "suspend_user"
+)
+
+var ErrDuplicateReport = errors.New("you have already reported this")
+
+// ErrReportResolved is returned by ResolveReports when the report has been
+// closed in the meantime, e.g. by another moderator.
+var ErrReportResolved = errors.New("report is already resolved")
+
+// ErrProtectedUser is returned when suspend_user targets a staff account.
+// Staff can only be suspended by someone with users:manage.
+var ErrProtectedUser = errors.New("staff accounts can't be suspended from the moderation queue")
+
+// protectedPermissions are the permissions that keep an account from being
+// suspended through a report.
+var protectedPermissions = []string{PermissionUsersManage, PermissionReportsManage}
+
+// hideableTargets maps the targets hide_content works on to their table.
+var hideableTargets = map[string]string{
+	ReportTargetArticle: "articles",
+	ReportTargetReview:  "reviews",
+}
+
+func IsValidReportTarget(targetType string) bool {
+	return targetType == ReportTargetArticle || targetType == ReportTargetReview || targetType == ReportTargetUser
+}
+
+// ValidateReportAction checks that action can be taken on a report about
+// targetType. Users can't be hidden, only suspended.
+func ValidateReportAction(targetType, action string) error {
+	switch action {
+	case ReportActionDismiss, ReportActionSuspendUser:
+		return nil
+	case ReportActionHideContent:
+		if _, ok := hideableTargets[targetType]; !ok {
+			return fmt.Errorf("a %s cannot be hidden", targetType)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid action %q", action)
+}
+
+type Report struct {
+	ID         int        `json:"id"`
+	ReporterID int        `json:"reporter_id"`
+	TargetType string     `json:"target_type"`
+	TargetID   int        `json:"target_id"`
+	Reason     string     `json:"reason"`
+	Status     string     `json:"status"`
+	Action     *string    `json:"action"`
+	ResolvedBy *int       `json:"resolved_by"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ReportGroup collects the open reports about one target.
+type ReportGroup struct {
+	TargetType      string    `json:"target_type"`
+	TargetID        int       `json:"target_id"`
+	ReportCount     int       `json:"report_count"`
+	FirstReportedAt time.Time `json:"first_reported_at"`
+	LastReportedAt  time.Time `json:"last_reported_at"`
+	Reports         []*Report `json:"reports"`
+}
+
+const reportColumns = `r.id, r.reporter_id, r.target_type, r.target_id, r.reason, r.status, r.action, r.resolved_by, r.resolved_at, r.created_at`
+
+func (r *Report) scanDest() []any {
+	return []any{
+		&r.ID,
+		&r.ReporterID,
+		&r.TargetType,
+		&r.TargetID,
+		&r.Reason,
+		&r.Status,
+		&r.Action,
+		&r.ResolvedBy,
+		&r.ResolvedAt,
+		&r.CreatedAt,
+	}
+}
+
+type PostgresReportStore struct {
+	db *sql.DB
+}
+
+func NewPostgresReportStore(db *sql.DB) *PostgresReportStore {
+	return &PostgresReportStore{db: db}
+}
+
+type ReportStore interface {
+	CreateReport(*Report) (*Report, error)
+	GetReportById(id int64) (*Report, error)
+	ListOpenReportGroups() ([]*ReportGroup, error)
+	ResolveReports(report *Report, action string, moderatorID int) (int, error)
+	UnhideContent(targetType string, targetID int64) error
+}
+
+func (pg *PostgresReportStore) CreateReport(report *Report) (*Report, error) {
+	query := `
+	INSERT INTO reports (reporter_id, target_type, target_id, reason)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, status, created_at`
+
+	err := pg.db.QueryRow(query, report.ReporterID, report.TargetType, report.TargetID, report.Reason).Scan(&report.ID, &report.Status, &report.CreatedAt)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicateReport
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func (pg *PostgresReportStore) GetReportById(id int64) (*Report, error) {
+	report := &Report{}
+	query := `
+	SELECT ` + reportColumns + `
+	FROM reports r
+	WHERE r.id = $1`
+
+	err := pg.db.QueryRow(query, id).Scan(report.scanDest()...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// ListOpenReportGroups returns the open reports grouped by what they are
+// about, the most reported targets first.
+func (pg *PostgresReportStore) ListOpenReportGroups() ([]*ReportGroup, error) {
+	query := `
+	SELECT ` + reportColumns + `
+	FROM reports r
+	WHERE r.status = 'open'
+	ORDER BY r.created_at, r.id`
+
+	rows, err := pg.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reports []*Report
+	for rows.Next() {
+		report := &Report{}
+		if err := rows.Scan(report.scanDest()...); err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groupReports(reports), nil
+}
+
+// groupReports groups reports, which must be sorted oldest first, by
+// target. Groups with more reports come first, ties go to the target that
+// has been waiting longest.
+func groupReports(reports []*Report) []*ReportGroup {
+	type key struct {
+		targetType string
+		targetID   int
+	}
+
+	groups := []*ReportGroup{}
+	byTarget := map[key]*ReportGroup{}
+	for _, report := range reports {
+		k := key{report.TargetType, report.TargetID}
+		group, ok := byTarget[k]
+		if !ok {
+			group = &ReportGroup{
+				TargetType:      report.TargetType,
+				TargetID:        report.TargetID,
+				FirstReportedAt: report.CreatedAt,
+			}
+			byTarget[k] = group
+			groups = append(groups, group)
+		}
+
+		group.Reports = append(group.Reports, report)
+		group.ReportCount++
+		group.LastReportedAt = report.CreatedAt
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].ReportCount > groups[j].ReportCount
+	})
+
+	return groups
+}
+
+// ResolveReports takes action on the target of report and closes every
+// open report about that target, recording moderatorID as the resolver. It
+// returns the number of reports closed, or ErrReportResolved when report is
+// no longer open. suspend_user suspends the target user, or the author of
+// the target article or review, unless they are staff (ErrProtectedUser).
+func (pg *PostgresReportStore) ResolveReports(report *Report, action string, moderatorID int) (int, error) {
+	if err := ValidateReportAction(report.TargetType, action); err != nil {
+		return 0, err
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// a moderator resolving the same target at the same time waits on these
+	// locks and then finds the reports closed
+	query := `
+	SELECT id FROM reports
+	WHERE target_type = $1 AND target_id = $2 AND status = 'open'
+	FOR UPDATE`
+
+	rows, err := tx.Query(query, report.TargetType, report.TargetID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	stillOpen := false
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		stillOpen = stillOpen || id == report.ID
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	if !stillOpen {
+		return 0, ErrReportResolved
+	}
+
+	switch action {
+	case ReportActionHideContent:
+		query := fmt.Sprintf(`UPDATE %s SET hidden_at = NOW() WHERE id = $1 AND hidden_at IS NULL`, hideableTargets[report.TargetType])
+		if _, err = tx.Exec(query, report.TargetID); err != nil {
+			return 0, err
+		}
+
+	case ReportActionSuspendUser:
+		userID := int64(report.TargetID)
+		switch report.TargetType {
+		case ReportTargetArticle:
+			err = tx.QueryRow(`SELECT author_id FROM articles WHERE id = $1`, report.TargetID).Scan(&userID)
+		case ReportTargetReview:
+			err = tx.QueryRow(`SELECT user_id FROM reviews WHERE id = $1`, report.TargetID).Scan(&userID)
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		var protected bool
+		query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM users u
+			INNER JOIN roles_permissions rp ON rp.role = u.role
+			WHERE u.id = $1 AND rp.permission = ANY($2)
+		)`
+		var permissions pgtype.TextArray
+		if err = permissions.Set(protectedPermissions); err != nil {
+			return 0, err
+		}
+		if err = tx.QueryRow(query, userID, &permissions).Scan(&protected); err != nil {
+			return 0, err
+		}
+		if protected {
+			return 0, ErrProtectedUser
+		}
+
+		suspension := Suspension{
+			Status: UserStatusSuspended,
+			Reason: fmt.Sprintf("reported %s: %s", report.TargetType, report.Reason),
+		}
+		if err = suspendUser(tx, userID, suspension); err != nil {
+			return 0, err
+		}
+	}
+
+	query = `
+	UPDATE reports
+	SET status = 'resolved', action = $1, resolved_by = $2, resolved_at = NOW()
+	WHERE target_type = $3 AND target_id = $4 AND status = 'open'`
+
+	result, err := tx.Exec(query, action, moderatorID, report.TargetType, report.TargetID)
+	if err != nil {
+		return 0, err
+	}
+
+	resolved, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(resolved), tx.Commit()
+}
+
+// UnhideContent makes content hidden by hide_content visible again. It
+// returns sql.ErrNoRows when the target doesn't exist or isn't hidden.
+func (pg *PostgresReportStore) UnhideContent(targetType string, targetID int64) error {
+	table, ok := hideableTargets[targetType]
+	if !ok {
+		return fmt.Errorf("a %s cannot be hidden", targetType)
+	}
+
+	query := fmt.Sprintf(`UPDATE %s SET hidden_at = NULL WHERE id = $1 AND hidden_at IS NOT NULL AND deleted_at IS NULL`, table)
+	return execOne(pg.db, query, targetID)
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupReports(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	report := func(id int, targetType string, targetID int) *Report {
+		return &Report{ID: id, TargetType: targetType, TargetID: targetID, CreatedAt: start.Add(time.Duration(id) * time.Hour)}
+	}
+
+	groups := groupReports([]*Report{
+		report(1, ReportTargetArticle, 5),
+		report(2, ReportTargetReview, 5),
+		report(3, ReportTargetUser, 9),
+		report(4, ReportTargetReview, 5),
+		report(5, ReportTargetArticle, 5),
+		report(6, ReportTargetReview, 5),
+	})
+
+	require.Len(t, groups, 3)
+	assert.Equal(t, ReportTargetReview, groups[0].TargetType)
+	assert.Equal(t, 3, groups[0].ReportCount)
+	assert.Equal(t, start.Add(2*time.Hour), groups[0].FirstReportedAt)
+	assert.Equal(t, start.Add(6*time.Hour), groups[0].LastReportedAt)
+
+	assert.Equal(t, ReportTargetArticle, groups[1].TargetType)
+	assert.Equal(t, 2, groups[1].ReportCount)
+	assert.Equal(t, ReportTargetUser, groups[2].TargetType)
+}
+
+func TestValidateReportAction(t *testing.T) {
+	assert.NoError(t, ValidateReportAction(ReportTargetArticle, ReportActionHideContent))
+	assert.NoError(t, ValidateReportAction(ReportTargetReview, ReportActionSuspendUser))
+	assert.NoError(t, ValidateReportAction(ReportTargetUser, ReportActionDismiss))
+	assert.Error(t, ValidateReportAction(ReportTargetUser, ReportActionHideContent))
+	assert.Error(t, ValidateReportAction(ReportTargetArticle, "delete"))
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrDuplicateReviewResponse = errors.New("this review already has a response")
+	ErrReviewResponseNotFound  = errors.New("review response not found")
+)
+
+// ReviewResponse is the public answer of an article's author to a review
+// of that article. A review has at most one.
+type ReviewResponse struct {
+	ReviewID  int       `json:"review_id"`
+	UserID    int       `json:"user_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// reviewResponseColumn builds the review's response as a json object, or
+// NULL when it has none. It is part of reviewColumns.
+const reviewResponseColumn = `(
+		SELECT json_build_object(
+			'review_id', rr.review_id,
+			'user_id', rr.user_id,
+			'body', rr.body,
+			'created_at', rr.created_at,
+			'updated_at', rr.updated_at
+		)
+		FROM review_responses rr
+		WHERE rr.review_id = r.id
+	)`
+
+// responseScanner scans reviewResponseColumn into review.Response.
+type responseScanner struct {
+	review *Review
+}
+
+func (s responseScanner) Scan(src any) error {
+	var js []byte
+	switch v := src.(type) {
+	case nil:
+		s.review.Response = nil
+		return nil
+	case []byte:
+		js = v
+	case string:
+		js = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a review response", src)
+	}
+
+	s.review.Response = &ReviewResponse{}
+	return json.Unmarshal(js, s.review.Response)
+}
+
+func (pg *PostgresReviewStore) CreateReviewResponse(response *ReviewResponse) error {
+	query := `
+	INSERT INTO review_responses (review_id, user_id, body)
+	VALUES ($1, $2, $3)
+	RETURNING created_at, updated_at`
+
+	err := pg.db.QueryRow(query, response.ReviewID, response.UserID, response.Body).Scan(&response.CreatedAt, &response.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicateReviewResponse
+	}
+	return err
+}
+
+// UpdateReviewResponse replaces the body of the response. It returns
+// ErrReviewResponseNotFound when the review has no response.
+func (pg *PostgresReviewStore) UpdateReviewResponse(response *ReviewResponse) error {
+	query := `
+	UPDATE review_responses
+	SET body = $1, updated_at = NOW()
+	WHERE review_id = $2
+	RETURNING user_id, created_at, updated_at`
+
+	err := pg.db.QueryRow(query, response.Body, response.ReviewID).Scan(&response.UserID, &response.CreatedAt, &response.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrReviewResponseNotFound
+	}
+	return err
+}
+
+// DeleteReviewResponse returns ErrReviewResponseNotFound when the review has
+// no response.
+func (pg *PostgresReviewStore) DeleteReviewResponse(reviewID int64) error {
+	err := execOne(pg.db, `DELETE FROM review_responses WHERE review_id = $1`, reviewID)
+	if err == sql.ErrNoRows {
+		return ErrReviewResponseNotFound
+	}
+	return err
+}
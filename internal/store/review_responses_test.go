@@ -0,0 +1,24 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseScanner(t *testing.T) {
+	review := &Review{Response: &ReviewResponse{}}
+	require.NoError(t, responseScanner{review}.Scan(nil))
+	assert.Nil(t, review.Response)
+
+	js := `{"review_id": 7, "user_id": 3, "body": "Thanks!", "created_at": "2025-03-01T10:00:00.123456+00:00", "updated_at": "2025-03-01T10:00:00.123456+00:00"}`
+	require.NoError(t, responseScanner{review}.Scan(js))
+	require.NotNil(t, review.Response)
+	assert.Equal(t, 7, review.Response.ReviewID)
+	assert.Equal(t, 3, review.Response.UserID)
+	assert.Equal(t, "Thanks!", review.Response.Body)
+	assert.Equal(t, 2025, review.Response.CreatedAt.Year())
+
+	assert.Error(t, responseScanner{review}.Scan(42))
+}
@@ -7,14 +7,45 @@ import (
 	"time"
 )
 
+// ErrDuplicateReview is returned when a user who already reviewed an
+// article tries to add a second review to it.
+var ErrDuplicateReview = errors.New("you have already reviewed this article")
+
 type Review struct {
-	ID         int       `json:"id"`
-	UserId     int       `json:"user_id"`
-	ArticleId  int       `json:"article_id"`
-	ReviewText string    `json:"review_text"`
-	Rating     int       `json:"rating"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID              int             `json:"id"`
+	UserId          int             `json:"user_id"`
+	ArticleId       int             `json:"article_id"`
+	ReviewText      string          `json:"review_text"`
+	Rating          int             `json:"rating"`
+	HelpfulCount    int             `json:"helpful_count"`
+	NotHelpfulCount int             `json:"not_helpful_count"`
+	Response        *ReviewResponse `json:"response"`
+	Version         int             `json:"version"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// reviewColumns is the column list every review query selects, in the
+// order scanDest expects them.
+const reviewColumns = `r.id, r.user_id, r.article_id, r.review_text, r.rating, r.version, r.created_at, r.updated_at,
+	(SELECT count(*) FROM review_votes v WHERE v.review_id = r.id AND v.helpful),
+	(SELECT count(*) FROM review_votes v WHERE v.review_id = r.id AND NOT v.helpful),
+	` + reviewResponseColumn
+
+func (r *Review) scanDest() []any {
+	return []any{
+		&r.ID,
+		&r.UserId,
+		&r.ArticleId,
+		&r.ReviewText,
+		&r.Rating,
+		&r.Version,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+		&r.HelpfulCount,
+		&r.NotHelpfulCount,
+		responseScanner{r},
+	}
 }
 
 type PostgresReviewStore struct {
@@ -28,17 +59,29 @@ func NewPostgresReviewStore(db *sql.DB) *PostgresReviewStore {
 type ReviewStore interface {
 	CreateReview(*Review) (*Review, error)
 	GetReviewById(id int64) (*Review, error)
+	GetDeletedReviewById(id int64) (*Review, error)
 	UpdateReview(*Review) error
-	DeleteReview(id int64) error
+	DeleteReview(id int64, version int) error
+	RestoreReview(id int64) error
+	ListArticleReviews(articleID int64, filter ReviewFilter) ([]*Review, Metadata, error)
+	GetRatingSummary(articleID int64) (*RatingSummary, error)
+	VoteReview(reviewID int64, userID int, helpful bool) error
+	DeleteReviewVote(reviewID int64, userID int) error
+	CreateReviewResponse(*ReviewResponse) error
+	UpdateReviewResponse(*ReviewResponse) error
+	DeleteReviewResponse(reviewID int64) error
 }
 
 func (pg *PostgresReviewStore) CreateReview(review *Review) (*Review, error) {
 	query := `
 	INSERT INTO reviews(user_id, article_id, review_text, rating, created_at, updated_at)
 	VALUES($1, $2, $3, $4, NOW(), NOW())
-	RETURNING id, created_at, updated_at;`
+	RETURNING id, version, created_at, updated_at;`
 
-	err := pg.db.QueryRow(query, review.UserId, review.ArticleId, review.ReviewText, review.Rating).Scan(&review.ID, &review.CreatedAt, &review.UpdatedAt)
+	err := pg.db.QueryRow(query, review.UserId, review.ArticleId, review.ReviewText, review.Rating).Scan(&review.ID, &review.Version, &review.CreatedAt, &review.UpdatedAt)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicateReview
+	}
 	if err != nil {
 		return nil, fmt.Errorf("create review: %v", err)
 	}
@@ -46,23 +89,27 @@ func (pg *PostgresReviewStore) CreateReview(review *Review) (*Review, error) {
 	return review, nil
 }
 
+// GetReviewById finds a review that is neither in the trash nor hidden by
+// moderation. Reviews of trashed articles are hidden along with the article.
 func (pg *PostgresReviewStore) GetReviewById(id int64) (*Review, error) {
+	return pg.getReview("r.id = $1 AND r.deleted_at IS NULL AND r.hidden_at IS NULL AND a.deleted_at IS NULL", id)
+}
+
+// GetDeletedReviewById finds a review that is in the trash.
+func (pg *PostgresReviewStore) GetDeletedReviewById(id int64) (*Review, error) {
+	return pg.getReview("r.id = $1 AND r.deleted_at IS NOT NULL", id)
+}
+
+func (pg *PostgresReviewStore) getReview(condition string, arg any) (*Review, error) {
 	review := &Review{}
 	query := `
-	SELECT id, user_id, article_id, review_text, rating, created_at, updated_at
-	FROM reviews
-	WHERE id = $1;`
-
-	row := pg.db.QueryRow(query, id)
-	err := row.Scan(
-		&review.ID,
-		&review.UserId,
-		&review.ArticleId,
-		&review.ReviewText,
-		&review.Rating,
-		&review.CreatedAt,
-		&review.UpdatedAt,
-	)
+	SELECT ` + reviewColumns + `
+	FROM reviews r
+	INNER JOIN articles a ON a.id = r.article_id
+	WHERE ` + condition
+
+	row := pg.db.QueryRow(query, arg)
+	err := row.Scan(review.scanDest()...)
 	if err == sql.ErrNoRows{
 		return nil, nil
 	}
@@ -72,46 +119,49 @@ func (pg *PostgresReviewStore) GetReviewById(id int64) (*Review, error) {
 	return review, nil
 }
 
+// UpdateReview saves the review if it is still at review.Version and fails
+// with ErrEditConflict otherwise.
 func (pg *PostgresReviewStore) UpdateReview(review *Review) error {
 	query := `UPDATE reviews
-	SET review_text = $1, rating = $2, updated_at = NOW()
-	WHERE id = $3`
-
-	result, err := pg.db.Exec(query, review.ReviewText, review.Rating, review.ID)
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
+	SET review_text = $1, rating = $2, updated_at = NOW(), version = version + 1
+	WHERE id = $3 AND version = $4 AND deleted_at IS NULL
+	RETURNING version, updated_at`
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("review with ID %d not found", review.ID)
+	err := pg.db.QueryRow(query, review.ReviewText, review.Rating, review.ID, review.Version).Scan(&review.Version, &review.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrEditConflict
 	}
 
-	return nil
+	return err
 }
 
 var ErrReviewNotfound = errors.New("review not found")
 
-func (pg *PostgresReviewStore) DeleteReview(id int64) error {
+// DeleteReview moves the review to the trash if it is still at version and
+// fails with ErrEditConflict otherwise.
+func (pg *PostgresReviewStore) DeleteReview(id int64, version int) error {
 	query := `
-	DELETE FROM reviews WHERE id = $1;`
+	UPDATE reviews SET deleted_at = NOW()
+	WHERE id = $1 AND version = $2 AND deleted_at IS NULL;`
 
-	result, err := pg.db.Exec(query, id)
-	if err != nil {
-		return err
+	err := execOne(pg.db, query, id, version)
+	if err == sql.ErrNoRows {
+		return ErrEditConflict
 	}
+	return err
+}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
+func (pg *PostgresReviewStore) RestoreReview(id int64) error {
+	query := `
+	UPDATE reviews SET deleted_at = NULL
+	WHERE id = $1 AND deleted_at IS NOT NULL;`
 
-	if rowsAffected == 0 {
+	err := execOne(pg.db, query, id)
+	if err == sql.ErrNoRows {
 		return ErrReviewNotfound
 	}
-	return nil
+	if isUniqueViolation(err) {
+		return ErrDuplicateReview
+	}
+	return err
 }
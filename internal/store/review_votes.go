@@ -0,0 +1,31 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var ErrVoteNotFound = errors.New("vote not found")
+
+// VoteReview records whether userID found the review helpful. Voting again
+// replaces the earlier vote.
+func (pg *PostgresReviewStore) VoteReview(reviewID int64, userID int, helpful bool) error {
+	query := `
+	INSERT INTO review_votes (review_id, user_id, helpful)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (review_id, user_id)
+	DO UPDATE SET helpful = EXCLUDED.helpful, updated_at = NOW()`
+
+	_, err := pg.db.Exec(query, reviewID, userID, helpful)
+	return err
+}
+
+// DeleteReviewVote withdraws userID's vote on the review. It returns
+// ErrVoteNotFound when there was no vote.
+func (pg *PostgresReviewStore) DeleteReviewVote(reviewID int64, userID int) error {
+	err := execOne(pg.db, `DELETE FROM review_votes WHERE review_id = $1 AND user_id = $2`, reviewID, userID)
+	if err == sql.ErrNoRows {
+		return ErrVoteNotFound
+	}
+	return err
+}
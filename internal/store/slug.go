@@ -0,0 +1,225 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgconn"
+	"golang.org/x/text/unicode/norm"
+)
+
+const maxSlugLength = 200
+
+// transliterations covers letters that don't decompose into an ASCII base
+// letter plus accents, most notably Cyrillic (Russian and Uzbek).
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'ø': "o", 'œ': "oe", 'đ': "d", 'ł': "l", 'þ': "th", 'ð': "d", 'ı': "i",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'ў': "o", 'қ': "q", 'ғ': "g", 'ҳ': "h", 'і': "i", 'ї': "yi", 'є': "ye",
+}
+
+// apostrophes are dropped instead of becoming separators, so "o‘zbek" and
+// "don't" stay one word.
+const apostrophes = "'’‘ʻʼ`"
+
+// Slugify turns a title into a lowercase ASCII slug made of letters, digits
+// and single dashes. Titles with nothing usable in them become "article".
+func Slugify(title string) string {
+	var b strings.Builder
+	pendingDash := false
+
+	write := func(s string) {
+		if s == "" {
+			return
+		}
+		if pendingDash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		pendingDash = false
+		b.WriteString(s)
+	}
+
+	for _, r := range strings.ToLower(title) {
+		if t, ok := transliterations[r]; ok {
+			write(t)
+			continue
+		}
+
+		if strings.ContainsRune(apostrophes, r) {
+			continue
+		}
+
+		// split accented letters into base letter + marks and keep the base
+		for _, d := range norm.NFKD.String(string(r)) {
+			switch {
+			case d < unicode.MaxASCII && (unicode.IsLetter(d) || unicode.IsDigit(d)):
+				write(string(d))
+			case unicode.Is(unicode.Mn, d):
+			default:
+				pendingDash = true
+			}
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > maxSlugLength {
+		slug = slug[:maxSlugLength]
+		if i := strings.LastIndexByte(slug, '-'); i > 0 {
+			slug = slug[:i]
+		}
+	}
+
+	if slug == "" {
+		return "article"
+	}
+	return slug
+}
+
+// nextSlug returns base, or base with the lowest "-N" suffix (N >= 2) that
+// is not in taken.
+func nextSlug(base string, taken []string) string {
+	used := make(map[string]bool, len(taken))
+	for _, slug := range taken {
+		used[slug] = true
+	}
+
+	if !used[base] {
+		return base
+	}
+
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", base, n)
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
+
+// maxSlugAttempts bounds how often a slug is picked again after a
+// concurrent transaction stored the same one first.
+const maxSlugAttempts = 5
+
+// isSlugViolation reports whether err is the articles_slug_key constraint
+// rejecting a slug that is already in use.
+func isSlugViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return isUniqueViolation(err) && errors.As(err, &pgErr) && pgErr.ConstraintName == "articles_slug_key"
+}
+
+// retrySlugConflict runs write, which picks a slug with uniqueSlug and
+// stores it, inside a savepoint. uniqueSlug can't see slugs that concurrent
+// transactions haven't committed yet, so when one of them stored the same
+// slug first, write is rolled back and run again; the retry sees that slug
+// as taken and picks the next suffix.
+func retrySlugConflict(tx *sql.Tx, write func() error) error {
+	for attempt := 1; ; attempt++ {
+		if _, err := tx.Exec(`SAVEPOINT slug`); err != nil {
+			return err
+		}
+
+		err := write()
+		if err == nil {
+			_, err = tx.Exec(`RELEASE SAVEPOINT slug`)
+			return err
+		}
+
+		if !isSlugViolation(err) || attempt == maxSlugAttempts {
+			return err
+		}
+
+		if _, err := tx.Exec(`ROLLBACK TO SAVEPOINT slug`); err != nil {
+			return err
+		}
+	}
+}
+
+// uniqueSlug picks a slug for the article derived from base that no other
+// article uses, now or in its slug history. articleID is 0 for new articles.
+func uniqueSlug(tx *sql.Tx, base string, articleID int) (string, error) {
+	query := `
+	SELECT slug FROM articles WHERE id <> $3 AND (slug = $1 OR slug LIKE $2)
+	UNION
+	SELECT slug FROM article_slugs WHERE article_id <> $3 AND (slug = $1 OR slug LIKE $2)`
+
+	rows, err := tx.Query(query, base, base+"-%", articleID)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var taken []string
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return "", err
+		}
+		taken = append(taken, slug)
+	}
+
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	return nextSlug(base, taken), nil
+}
+
+// renameSlug gives the article a new slug when its title changed and moves
+// the old one into the slug history. It must run inside the update
+// transaction, before the new title is written.
+func renameSlug(tx *sql.Tx, article *Article) error {
+	var title, slug string
+	err := tx.QueryRow(`SELECT title, slug FROM articles WHERE id = $1`, article.ID).Scan(&title, &slug)
+	if err != nil {
+		return err
+	}
+
+	article.Slug = slug
+	if title == article.Title {
+		return nil
+	}
+
+	return retrySlugConflict(tx, func() error {
+		newSlug, err := uniqueSlug(tx, Slugify(article.Title), article.ID)
+		if err != nil {
+			return err
+		}
+
+		if newSlug == slug {
+			return nil
+		}
+
+		_, err = tx.Exec(`
+		INSERT INTO article_slugs (slug, article_id)
+		VALUES ($1, $2)
+		ON CONFLICT (slug) DO NOTHING`, slug, article.ID)
+		if err != nil {
+			return err
+		}
+
+		// the article may be getting back a slug it used before
+		_, err = tx.Exec(`DELETE FROM article_slugs WHERE slug = $1 AND article_id = $2`, newSlug, article.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE articles SET slug = $1 WHERE id = $2`, newSlug, article.ID)
+		if err != nil {
+			return err
+		}
+
+		article.Slug = newSlug
+		return nil
+	})
+}
+
+// GetArticleByOldSlug looks slug up in the slug history and returns the
+// article that used it, or nil when slug was never used.
+func (pg *PostgresArticleStore) GetArticleByOldSlug(slug string) (*Article, error) {
+	return pg.getArticle("a.id = (SELECT s.article_id FROM article_slugs s WHERE s.slug = $1) AND a.deleted_at IS NULL", slug)
+}
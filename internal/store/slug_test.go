@@ -0,0 +1,57 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{title: "How to test your code", want: "how-to-test-your-code"},
+		{title: "  Go 1.24: what's new?! ", want: "go-1-24-whats-new"},
+		{title: "Crème brûlée à la française", want: "creme-brulee-a-la-francaise"},
+		{title: "Straße in Łódź", want: "strasse-in-lodz"},
+		{title: "Привет, мир", want: "privet-mir"},
+		{title: "O‘zbekiston bo‘ylab sayohat", want: "ozbekiston-boylab-sayohat"},
+		{title: "Ўзбек тили", want: "ozbek-tili"},
+		{title: "!!!", want: "article"},
+		{title: "日本語", want: "article"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			assert.Equal(t, tt.want, Slugify(tt.title))
+		})
+	}
+}
+
+func TestSlugifyTruncatesAtWordBoundary(t *testing.T) {
+	slug := Slugify(strings.Repeat("word ", 100))
+
+	assert.LessOrEqual(t, len(slug), maxSlugLength)
+	assert.False(t, strings.HasSuffix(slug, "-"))
+	assert.True(t, strings.HasSuffix(slug, "word"))
+}
+
+func TestNextSlug(t *testing.T) {
+	assert.Equal(t, "go", nextSlug("go", nil))
+	assert.Equal(t, "go-2", nextSlug("go", []string{"go"}))
+	assert.Equal(t, "go-4", nextSlug("go", []string{"go", "go-2", "go-3"}))
+	assert.Equal(t, "go-2", nextSlug("go", []string{"go", "go-3", "go-lang"}))
+}
+
+func TestIsSlugViolation(t *testing.T) {
+	assert.True(t, isSlugViolation(&pgconn.PgError{Code: "23505", ConstraintName: "articles_slug_key"}))
+	assert.True(t, isSlugViolation(fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505", ConstraintName: "articles_slug_key"})))
+	assert.False(t, isSlugViolation(&pgconn.PgError{Code: "23505", ConstraintName: "article_slugs_pkey"}))
+	assert.False(t, isSlugViolation(&pgconn.PgError{Code: "23503", ConstraintName: "articles_slug_key"}))
+	assert.False(t, isSlugViolation(errors.New("boom")))
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgtype"
+)
+
+const (
+	maxTagLength      = 50
+	maxTagsPerArticle = 10
+)
+
+type TagCount struct {
+	Name         string `json:"name"`
+	ArticleCount int    `json:"article_count"`
+}
+
+// NormalizeTag lowercases a tag and joins its words with single dashes, so
+// "GoLang", "golang " and " Golang" are all the same tag.
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), "-"))
+}
+
+// NormalizeTags normalizes every tag, drops empty ones and duplicates and
+// checks the limits on tag length and count.
+func NormalizeTags(names []string) ([]string, error) {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		tag := NormalizeTag(name)
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+
+	if len(tags) > maxTagsPerArticle {
+		return nil, fmt.Errorf("an article can have at most %d tags", maxTagsPerArticle)
+	}
+
+	return tags, nil
+}
+
+// textArray scans a Postgres text[] column into a string slice.
+type textArray []string
+
+func (t *textArray) Scan(src any) error {
+	var array pgtype.TextArray
+	if err := array.Scan(src); err != nil {
+		return err
+	}
+
+	values := []string{}
+	if err := array.AssignTo(&values); err != nil {
+		return err
+	}
+	*t = values
+	return nil
+}
+
+// setArticleTags replaces the article's tags, creating tags that don't
+// exist yet. It runs inside the caller's transaction.
+func setArticleTags(tx *sql.Tx, articleID int, tags []string) error {
+	_, err := tx.Exec(`DELETE FROM articles_tags WHERE article_id = $1`, articleID)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		query := `
+		WITH tag AS (
+			INSERT INTO tags (name) VALUES ($1)
+			ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+			RETURNING id
+		)
+		INSERT INTO articles_tags (article_id, tag_id)
+		SELECT $2, id FROM tag
+		ON CONFLICT DO NOTHING`
+
+		_, err := tx.Exec(query, tag, articleID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type PostgresTagStore struct {
+	db *sql.DB
+}
+
+func NewPostgresTagStore(db *sql.DB) *PostgresTagStore {
+	return &PostgresTagStore{db: db}
+}
+
+type TagStore interface {
+	ListTags() ([]*TagCount, error)
+}
+
+// ListTags returns every tag used by at least one published article, most
+// used first.
+func (pg *PostgresTagStore) ListTags() ([]*TagCount, error) {
+	query := `
+	SELECT t.name, COUNT(a.id)
+	FROM tags t
+	INNER JOIN articles_tags art ON art.tag_id = t.id
+	INNER JOIN articles a ON a.id = art.article_id AND a.status = 'published' AND a.deleted_at IS NULL AND a.hidden_at IS NULL
+	GROUP BY t.name
+	ORDER BY COUNT(a.id) DESC, t.name`
+
+	rows, err := pg.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*TagCount{}
+	for rows.Next() {
+		tag := &TagCount{}
+		if err := rows.Scan(&tag.Name, &tag.ArticleCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
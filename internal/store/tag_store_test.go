@@ -0,0 +1,25 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{"GoLang", "golang ", "  Machine   Learning", "", "   ", "postgres"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"golang", "machine-learning", "postgres"}, tags)
+
+	_, err = NormalizeTags([]string{strings.Repeat("x", maxTagLength+1)})
+	assert.Error(t, err)
+
+	tooMany := make([]string, maxTagsPerArticle+1)
+	for i := range tooMany {
+		tooMany[i] = strings.Repeat("t", i+1)
+	}
+	_, err = NormalizeTags(tooMany)
+	assert.Error(t, err)
+}
@@ -1,7 +1,11 @@
 package store
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
 	"time"
 	"github.com/htojiddinov77-png/Articles/internal/tokens"
 )
@@ -20,6 +24,39 @@ type TokenStore interface {
 	GetTokenByHash(hash []byte) (*tokens.Token, error)
 	CreateNewToken(userId int, ttl time.Duration, scope string) (*tokens.Token, error)
 	DeleteAllTokensForUser(userID int, scope string) error
+	DeleteToken(hash []byte) error
+	LastTokenCreatedAt(userID int, scope string) (*time.Time, error)
+	MarkTokenUsed(hash []byte) error
+	ListSessions(userID int, currentHash []byte) ([]*Session, error)
+	DeleteSession(userID int, id string) error
+	CreateSession(userID int, userAgent, ip string) (access, refresh *tokens.Token, err error)
+	RotateRefreshToken(plaintext, userAgent, ip string) (access, refresh *tokens.Token, err error)
+}
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused means a refresh token was presented a second
+	// time, so it has probably been stolen. Its whole family is revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// execer is what inserting a token needs from *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// Session is a login as its owner sees it: a token family that still has a
+// live access or refresh token. The tokens themselves are never shown again;
+// Current marks the session making the request. Expiry is when the session
+// ends unless it is refreshed, which is the expiry of its refresh token.
+type Session struct {
+	ID         string     `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Expiry     time.Time  `json:"expiry"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	Current    bool       `json:"current"`
 }
 
 func (t *PostgresTokenStore) CreateNewToken(userID int, ttl time.Duration, scope string)(*tokens.Token, error) {
@@ -32,11 +69,15 @@ func (t *PostgresTokenStore) CreateNewToken(userID int, ttl time.Duration, scope
 }
 
 func (t *PostgresTokenStore) Insert(token *tokens.Token) error{
+	return insertToken(t.db, token)
+}
+
+func insertToken(db execer, token *tokens.Token) error {
 	query := `
-	INSERT INTO tokens (hash, user_id, expiry, scope)
-	VALUES($1, $2, $3, $4)`
+	INSERT INTO tokens (hash, user_id, expiry, scope, user_agent, ip, family_id)
+	VALUES($1, $2, $3, $4, $5, $6, NULLIF($7, ''))`
 
-	_, err := t.db.Exec(query, token.Hash, token.UserID, token.Expiry, token.Scope)
+	_, err := db.Exec(query, token.Hash, token.UserID, token.Expiry, token.Scope, token.UserAgent, token.IP, token.FamilyID)
 	return err
 }
 
@@ -75,4 +116,275 @@ func (t *PostgresTokenStore) DeleteAllTokensForUser(userID int, scope string) er
 
 	_,err := t.db.Exec(query, scope, userID)
 	return err
-}
\ No newline at end of file
+}
+
+// DeleteToken revokes the token and every other token of its family, so a
+// logout also invalidates the session's refresh token.
+func (t *PostgresTokenStore) DeleteToken(hash []byte) error {
+	query := `
+	WITH target AS (
+		SELECT hash, family_id FROM tokens WHERE hash = $1
+	)
+	DELETE FROM tokens t
+	USING target
+	WHERE t.hash = target.hash OR t.family_id = target.family_id`
+
+	_, err := t.db.Exec(query, hash)
+	return err
+}
+
+// MarkTokenUsed records that the token was just used. The write is skipped
+// when it was already recorded in the last minute, so busy clients don't
+// cause a write on every request.
+func (t *PostgresTokenStore) MarkTokenUsed(hash []byte) error {
+	query := `
+	UPDATE tokens
+	SET last_used_at = NOW()
+	WHERE hash = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`
+
+	_, err := t.db.Exec(query, hash)
+	return err
+}
+
+// sessionToken is a live token as ListSessions reads it.
+type sessionToken struct {
+	id         int64
+	familyID   string
+	loginAt    time.Time
+	createdAt  time.Time
+	lastUsedAt *time.Time
+	expiry     time.Time
+	userAgent  string
+	ip         string
+	current    bool
+}
+
+// ListSessions returns the user's sessions, the most recently used first.
+// A session stays listed while its refresh token works, also when its
+// access token has already expired.
+func (t *PostgresTokenStore) ListSessions(userID int, currentHash []byte) ([]*Session, error) {
+	query := `
+	SELECT t.id, COALESCE(t.family_id, ''),
+		COALESCE((SELECT MIN(f.created_at) FROM tokens f WHERE f.family_id = t.family_id), t.created_at),
+		t.created_at, t.last_used_at, t.expiry, t.user_agent, t.ip, t.hash = $4
+	FROM tokens t
+	WHERE t.user_id = $1 AND t.scope IN ($2, $3) AND t.used_at IS NULL AND t.expiry > NOW()
+	ORDER BY t.id`
+
+	rows, err := t.db.Query(query, userID, tokens.ScopeAuth, tokens.ScopeRefresh, currentHash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var live []sessionToken
+	for rows.Next() {
+		var token sessionToken
+		err := rows.Scan(
+			&token.id,
+			&token.familyID,
+			&token.loginAt,
+			&token.createdAt,
+			&token.lastUsedAt,
+			&token.expiry,
+			&token.userAgent,
+			&token.ip,
+			&token.current,
+		)
+		if err != nil {
+			return nil, err
+		}
+		live = append(live, token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return groupSessions(live), nil
+}
+
+// groupSessions folds live tokens, sorted oldest first, into one session
+// per family. Tokens from before families existed are a session each. A
+// token issued after the login means the session was refreshed, which
+// counts as using it.
+func groupSessions(live []sessionToken) []*Session {
+	sessions := []*Session{}
+	byID := map[string]*Session{}
+	for _, token := range live {
+		id := token.familyID
+		if id == "" {
+			id = strconv.FormatInt(token.id, 10)
+		}
+
+		session, ok := byID[id]
+		if !ok {
+			session = &Session{ID: id, CreatedAt: token.loginAt}
+			byID[id] = session
+			sessions = append(sessions, session)
+		}
+
+		usedAt := token.lastUsedAt
+		if usedAt == nil && token.createdAt.After(token.loginAt) {
+			usedAt = &token.createdAt
+		}
+		if usedAt != nil && (session.LastUsedAt == nil || usedAt.After(*session.LastUsedAt)) {
+			session.LastUsedAt = usedAt
+		}
+
+		if token.expiry.After(session.Expiry) {
+			session.Expiry = token.expiry
+		}
+		session.UserAgent = token.userAgent
+		session.IP = token.ip
+		session.Current = session.Current || token.current
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return lastActivity(sessions[i]).After(lastActivity(sessions[j]))
+	})
+
+	return sessions
+}
+
+func lastActivity(session *Session) time.Time {
+	if session.LastUsedAt != nil {
+		return *session.LastUsedAt
+	}
+	return session.CreatedAt
+}
+
+// DeleteSession revokes every token of one of the user's sessions. It
+// returns sql.ErrNoRows when the user has no such session.
+func (t *PostgresTokenStore) DeleteSession(userID int, id string) error {
+	query := `
+	DELETE FROM tokens
+	WHERE user_id = $1 AND scope IN ($3, $4)
+		AND (family_id = $2 OR (family_id IS NULL AND id::text = $2))`
+
+	return execOne(t.db, query, userID, id, tokens.ScopeAuth, tokens.ScopeRefresh)
+}
+
+// CreateSession issues an access token and a refresh token for a new login.
+// Both start a new token family.
+func (t *PostgresTokenStore) CreateSession(userID int, userAgent, ip string) (*tokens.Token, *tokens.Token, error) {
+	familyID, err := tokens.NewFamilyID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := t.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	access, refresh, err := insertTokenPair(tx, userID, familyID, userAgent, ip)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return access, refresh, tx.Commit()
+}
+
+// RotateRefreshToken trades a refresh token for a new access and refresh
+// token in the same family. A refresh token works once: presenting a used
+// one revokes the family and returns ErrRefreshTokenReused.
+func (t *PostgresTokenStore) RotateRefreshToken(plaintext, userAgent, ip string) (*tokens.Token, *tokens.Token, error) {
+	hash := sha256.Sum256([]byte(plaintext))
+
+	tx, err := t.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		userID   int
+		expiry   time.Time
+		familyID sql.NullString
+		usedAt   *time.Time
+	)
+	query := `
+	SELECT user_id, expiry, family_id, used_at
+	FROM tokens
+	WHERE hash = $1 AND scope = $2
+	FOR UPDATE`
+
+	err = tx.QueryRow(query, hash[:], tokens.ScopeRefresh).Scan(&userID, &expiry, &familyID, &usedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !time.Now().Before(expiry) || !familyID.Valid {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	if usedAt != nil {
+		if _, err = tx.Exec(`DELETE FROM tokens WHERE family_id = $1`, familyID.String); err != nil {
+			return nil, nil, err
+		}
+		if err = tx.Commit(); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	// the used token is kept until it expires so a replay can be detected
+	if _, err = tx.Exec(`UPDATE tokens SET used_at = NOW() WHERE hash = $1`, hash[:]); err != nil {
+		return nil, nil, err
+	}
+
+	// the new access token replaces the old one, so the family stays a
+	// single session
+	query = `DELETE FROM tokens WHERE family_id = $1 AND scope = $2`
+	if _, err = tx.Exec(query, familyID.String, tokens.ScopeAuth); err != nil {
+		return nil, nil, err
+	}
+
+	access, refresh, err := insertTokenPair(tx, userID, familyID.String, userAgent, ip)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return access, refresh, tx.Commit()
+}
+
+func insertTokenPair(tx *sql.Tx, userID int, familyID, userAgent, ip string) (*tokens.Token, *tokens.Token, error) {
+	access, err := tokens.GenerateToken(userID, tokens.AccessTokenTTL, tokens.ScopeAuth)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refresh, err := tokens.GenerateToken(userID, tokens.RefreshTokenTTL, tokens.ScopeRefresh)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, token := range []*tokens.Token{access, refresh} {
+		token.FamilyID = familyID
+		token.UserAgent = userAgent
+		token.IP = ip
+		if err := insertToken(tx, token); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return access, refresh, nil
+}
+
+// LastTokenCreatedAt returns when the user's newest token of scope was
+// issued, or nil when they have none.
+func (t *PostgresTokenStore) LastTokenCreatedAt(userID int, scope string) (*time.Time, error) {
+	var createdAt *time.Time
+	query := `
+	SELECT MAX(created_at)
+	FROM tokens
+	WHERE user_id = $1 AND scope = $2`
+
+	err := t.db.QueryRow(query, userID, scope).Scan(&createdAt)
+	return createdAt, err
+}
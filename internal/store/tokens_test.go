@@ -0,0 +1,51 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupSessions(t *testing.T) {
+	login := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	used := login.Add(5 * time.Minute)
+
+	sessions := groupSessions([]sessionToken{
+		// logged in, access token still valid
+		{id: 1, familyID: "A", loginAt: login, createdAt: login, lastUsedAt: &used, expiry: login.Add(15 * time.Minute), userAgent: "curl", current: true},
+		{id: 2, familyID: "A", loginAt: login, createdAt: login, expiry: login.Add(30 * 24 * time.Hour), userAgent: "curl"},
+		// a token from before families existed
+		{id: 3, loginAt: login.Add(time.Hour), createdAt: login.Add(time.Hour), expiry: login.Add(25 * time.Hour), userAgent: "legacy"},
+	})
+
+	require.Len(t, sessions, 2)
+	assert.Equal(t, "3", sessions[0].ID)
+	assert.Nil(t, sessions[0].LastUsedAt)
+
+	assert.Equal(t, "A", sessions[1].ID)
+	assert.Equal(t, login, sessions[1].CreatedAt)
+	assert.Equal(t, &used, sessions[1].LastUsedAt)
+	assert.Equal(t, login.Add(30*24*time.Hour), sessions[1].Expiry)
+	assert.True(t, sessions[1].Current)
+}
+
+func TestGroupSessionsKeepsFamilyWithOnlyRefreshToken(t *testing.T) {
+	login := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	refreshed := login.Add(2 * time.Hour)
+
+	// the access token of the last refresh has expired and isn't read, only
+	// the refresh token issued with it is still live
+	sessions := groupSessions([]sessionToken{
+		{id: 7, familyID: "B", loginAt: login, createdAt: refreshed, expiry: refreshed.Add(30 * 24 * time.Hour), userAgent: "phone", ip: "203.0.113.7"},
+	})
+
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "B", sessions[0].ID)
+	assert.Equal(t, login, sessions[0].CreatedAt)
+	assert.Equal(t, &refreshed, sessions[0].LastUsedAt)
+	assert.Equal(t, refreshed.Add(30*24*time.Hour), sessions[0].Expiry)
+	assert.Equal(t, "phone", sessions[0].UserAgent)
+	assert.False(t, sessions[0].Current)
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	TrashTypeArticle = "article"
+	TrashTypeReview  = "review"
+)
+
+// TrashItem is a soft-deleted article or review. Title is the title of the
+// article, for reviews the one they were written for.
+type TrashItem struct {
+	Type      string    `json:"type"`
+	ID        int       `json:"id"`
+	ArticleID int       `json:"article_id"`
+	Title     string    `json:"title"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+type PostgresTrashStore struct {
+	db *sql.DB
+}
+
+func NewPostgresTrashStore(db *sql.DB) *PostgresTrashStore {
+	return &PostgresTrashStore{db: db}
+}
+
+type TrashStore interface {
+	ListTrash(userID int) ([]*TrashItem, error)
+	PurgeTrash(deletedBefore time.Time) (int64, error)
+}
+
+// ListTrash returns the articles and reviews userID deleted, most recently
+// deleted first. PurgeAt is left for the caller, who knows the retention.
+func (pg *PostgresTrashStore) ListTrash(userID int) ([]*TrashItem, error) {
+	query := `
+	SELECT 'article', a.id, a.id, a.title, a.deleted_at
+	FROM articles a
+	WHERE a.author_id = $1 AND a.deleted_at IS NOT NULL
+	UNION ALL
+	SELECT 'review', r.id, a.id, a.title, r.deleted_at
+	FROM reviews r
+	INNER JOIN articles a ON a.id = r.article_id
+	WHERE r.user_id = $1 AND r.deleted_at IS NOT NULL
+	ORDER BY 5 DESC, 2 DESC`
+
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []*TrashItem{}
+	for rows.Next() {
+		item := &TrashItem{}
+		err = rows.Scan(&item.Type, &item.ID, &item.ArticleID, &item.Title, &item.DeletedAt)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// PurgeTrash permanently deletes the articles and reviews that were put in
+// the trash before deletedBefore and returns how many rows went. Purging an
+// article takes its paragraphs, revisions and reviews with it.
+func (pg *PostgresTrashStore) PurgeTrash(deletedBefore time.Time) (int64, error) {
+	var purged int64
+	for _, query := range []string{
+		`DELETE FROM reviews WHERE deleted_at < $1`,
+		`DELETE FROM articles WHERE deleted_at < $1`,
+	} {
+		result, err := pg.db.Exec(query, deletedBefore)
+		if err != nil {
+			return purged, err
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+	}
+
+	return purged, nil
+}
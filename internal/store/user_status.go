@@ -0,0 +1,30 @@
+package store
+
+import "time"
+
+// IsLockedOut reports whether the account may not be used at now: it is
+// banned, or suspended and the suspension has not run out yet.
+func (u *User) IsLockedOut(now time.Time) bool {
+	switch u.Status {
+	case UserStatusBanned:
+		return true
+	case UserStatusSuspended:
+		return u.SuspendedUntil == nil || now.Before(*u.SuspendedUntil)
+	}
+	return false
+}
+
+// LockoutMessage explains to a locked out user why they can't sign in.
+func (u *User) LockoutMessage() string {
+	msg := "your account has been suspended"
+	if u.Status == UserStatusBanned {
+		msg = "your account has been banned"
+	} else if u.SuspendedUntil != nil {
+		msg += " until " + u.SuspendedUntil.UTC().Format(time.RFC3339)
+	}
+
+	if u.SuspensionReason != nil && *u.SuspensionReason != "" {
+		msg += ": " + *u.SuspensionReason
+	}
+	return msg
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserIsLockedOut(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	assert.False(t, (&User{Status: UserStatusActive}).IsLockedOut(now))
+	assert.True(t, (&User{Status: UserStatusBanned}).IsLockedOut(now))
+	assert.True(t, (&User{Status: UserStatusSuspended}).IsLockedOut(now))
+	assert.True(t, (&User{Status: UserStatusSuspended, SuspendedUntil: &later}).IsLockedOut(now))
+	assert.False(t, (&User{Status: UserStatusSuspended, SuspendedUntil: &earlier}).IsLockedOut(now))
+}
+
+func TestUserLockoutMessage(t *testing.T) {
+	reason := "spam"
+	until := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	assert.Equal(t, "your account has been suspended", (&User{Status: UserStatusSuspended}).LockoutMessage())
+	assert.Equal(t, "your account has been suspended until 2030-01-02T03:04:05Z: spam",
+		(&User{Status: UserStatusSuspended, SuspendedUntil: &until, SuspensionReason: &reason}).LockoutMessage())
+	assert.Equal(t, "your account has been banned: spam", (&User{Status: UserStatusBanned, SuspensionReason: &reason}).LockoutMessage())
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/htojiddinov77-png/Articles/internal/tokens"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -40,13 +41,54 @@ func (p *password) Matches(plaintTextPassword string) (bool, error) {
 }
 
 type User struct {
-	ID           int       `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	PasswordHash password  `json:"-"`
-	Bio          string    `json:"bio"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           int      `json:"id"`
+	Username     string   `json:"username"`
+	Email        string   `json:"email"`
+	PasswordHash password `json:"-"`
+	Bio          string   `json:"bio"`
+	Role         string   `json:"role"`
+	Status       string   `json:"status"`
+	Activated    bool     `json:"activated"`
+	// SuspendedUntil and SuspensionReason describe the current suspension
+	// or ban. A suspension without an end lasts until it is lifted.
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty"`
+	SuspensionReason *string    `json:"suspension_reason,omitempty"`
+	Version          int        `json:"version"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Permissions is only populated for the authenticated user of a request.
+	Permissions Permissions `json:"-"`
+	// APIKey is set when the request was authenticated with an API key.
+	APIKey *APIKey `json:"-"`
+}
+
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusBanned    = "banned"
+)
+
+// userColumns is the column list every user query selects, in the order
+// scanDest expects them.
+const userColumns = `u.id, u.username, u.email, u.password_hash, u.bio, u.role, u.status, u.activated, u.suspended_until, u.suspension_reason, u.version, u.created_at, u.updated_at`
+
+func (u *User) scanDest() []any {
+	return []any{
+		&u.ID,
+		&u.Username,
+		&u.Email,
+		&u.PasswordHash.hash,
+		&u.Bio,
+		&u.Role,
+		&u.Status,
+		&u.Activated,
+		&u.SuspendedUntil,
+		&u.SuspensionReason,
+		&u.Version,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+	}
 }
 
 var AnonymousUser = &User{}
@@ -55,6 +97,18 @@ func (u *User) IsAnonymous() bool {
 	return u == AnonymousUser
 }
 
+func (u *User) Can(permission string) bool {
+	return !u.IsAnonymous() && u.Permissions.Include(permission)
+}
+
+// HasScope reports whether the request may use scope. Only API keys are
+// limited by scopes, sessions can use everything their role allows.
+func (u *User) HasScope(scope string) bool {
+	return u.APIKey == nil || u.APIKey.HasScope(scope)
+}
+
+var ErrRoleNotFound = errors.New("role not found")
+
 type PostgresUserStore struct {
 	db *sql.DB
 }
@@ -71,15 +125,28 @@ type UserStore interface {
 	UpdateUser(*User) error
 	DeleteUser(id int64) error
 	GetUserToken(scope, tokenPlaintext string) (*User, error)
+	GetPermissionsForUser(userID int64) (Permissions, error)
+	SetUserRole(userID int64, role string) error
+	SuspendUser(userID int64, suspension Suspension) error
+	UnsuspendUser(userID int64) error
+	ActivateUser(userID int64) error
+}
+
+// Suspension locks an account out. Status is UserStatusSuspended or
+// UserStatusBanned; Until is ignored for bans.
+type Suspension struct {
+	Status string
+	Until  *time.Time
+	Reason string
 }
 
 func (pg *PostgresUserStore) CreateUser(user *User) error {
 	query := `
     INSERT INTO users (username, email, password_hash, bio, created_at, updated_at)
     VALUES ($1, $2, $3, $4, NOW(), NOW())
-    RETURNING id;
+    RETURNING id, role, status, activated, version, created_at, updated_at;
     `
-	err := pg.db.QueryRow(query, user.Username, user.Email, user.PasswordHash.hash, user.Bio).Scan(&user.ID)
+	err := pg.db.QueryRow(query, user.Username, user.Email, user.PasswordHash.hash, user.Bio).Scan(&user.ID, &user.Role, &user.Status, &user.Activated, &user.Version, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		return err
 	}
@@ -91,18 +158,11 @@ func (pg *PostgresUserStore) GetUserByEmail(email string) (*User, error) {
 	user := &User{
 		PasswordHash: password{},
 	}
-	query := `SELECT id, username, email, bio, created_at, updated_at
-	FROM users
-	WHERE email = $1`
+	query := `SELECT ` + userColumns + `
+	FROM users u
+	WHERE u.email = $1`
 
-	err := pg.db.QueryRow(query, email).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.Bio,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	err := pg.db.QueryRow(query, email).Scan(user.scanDest()...)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
@@ -119,19 +179,11 @@ func (pg *PostgresUserStore) GetUserByUsername(username string) (*User, error) {
 	user := &User{
 		PasswordHash: password{},
 	}
-	query := `SELECT id, username, password_hash, email, bio, created_at, updated_at
-	FROM users
-	WHERE username = $1`
+	query := `SELECT ` + userColumns + `
+	FROM users u
+	WHERE u.username = $1`
 
-		err := pg.db.QueryRow(query, username).Scan(
-		&user.ID,
-		&user.Username,
-		&user.PasswordHash.hash,
-		&user.Email,
-		&user.Bio,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+		err := pg.db.QueryRow(query, username).Scan(user.scanDest()...)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -151,21 +203,13 @@ func (pg *PostgresUserStore) GetUserByUsername(username string) (*User, error) {
 func (pg *PostgresUserStore) GetUserById(id int64) (*User, error) {
 	user := &User{}
 	query := `
-	SELECT id, username, password_hash, email, bio, created_at, updated_at
-	FROM users 
-	WHERE id = $1;
+	SELECT ` + userColumns + `
+	FROM users u
+	WHERE u.id = $1;
 	`
 
 	row := pg.db.QueryRow(query, id)
-	err := row.Scan(
-		&user.ID,
-		&user.Username,
-		&user.PasswordHash.hash,
-		&user.Email,
-		&user.Bio,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	err := row.Scan(user.scanDest()...)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -175,43 +219,29 @@ func (pg *PostgresUserStore) GetUserById(id int64) (*User, error) {
 	return user, nil
 }
 
+// UpdateUser saves the user if it is still at user.Version and fails with
+// ErrEditConflict otherwise.
 func (pg *PostgresUserStore) UpdateUser(user *User) error {
-	tx, err := pg.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
 	query := `
 	UPDATE users 
-	SET username = $1, email = $2, password_hash = $3, bio = $4, updated_at = NOW()
-	WHERE id = $5;
+	SET username = $1, email = $2, password_hash = $3, bio = $4, updated_at = NOW(), version = version + 1
+	WHERE id = $5 AND version = $6
+	RETURNING version, updated_at;
 	`
 
-	result, err := tx.Exec(query,
+	err := pg.db.QueryRow(query,
 		user.Username,
 		user.Email,
 		user.PasswordHash.hash,
 		user.Bio,
 		user.ID,
-	)
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
-		return fmt.Errorf("user with ID %d not found", user.ID)
+		user.Version,
+	).Scan(&user.Version, &user.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrEditConflict
 	}
 
-	if err := tx.Commit(); err != nil {
-		return err
-	}
-	return nil
+	return err
 }
 
 func (pg *PostgresUserStore) DeleteUser(id int64) error {
@@ -238,7 +268,7 @@ func (pg *PostgresUserStore) GetUserToken(scope, tokenPlaintext string) (*User,
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
 
 	query := `
-	SELECT u.id, u.username, u.email, u.password_hash, u.bio, u.created_at, u.updated_at
+	SELECT ` + userColumns + `
 	FROM users u
 	INNER JOIN tokens t ON t.user_id = u.id
 	WHERE t.hash = $1 AND t.scope = $2 AND t.expiry > $3;`
@@ -247,15 +277,7 @@ func (pg *PostgresUserStore) GetUserToken(scope, tokenPlaintext string) (*User,
 		PasswordHash: password{},
 	}
 
-	err := pg.db.QueryRow(query, tokenHash[:], scope, time.Now()).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.PasswordHash.hash,
-		&user.Bio,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	err := pg.db.QueryRow(query, tokenHash[:], scope, time.Now()).Scan(user.scanDest()...)
 
 	if err == sql.ErrNoRows	{
 		return nil, nil
@@ -268,6 +290,152 @@ func (pg *PostgresUserStore) GetUserToken(scope, tokenPlaintext string) (*User,
 	return user, nil
 }
 
+func (pg *PostgresUserStore) GetPermissionsForUser(userID int64) (Permissions, error) {
+	query := `
+	SELECT rp.permission
+	FROM roles_permissions rp
+	INNER JOIN users u ON u.role = rp.role
+	WHERE u.id = $1
+	ORDER BY rp.permission;`
+
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions Permissions
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+func (pg *PostgresUserStore) SetUserRole(userID int64, role string) error {
+	var exists bool
+	err := pg.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM roles WHERE name = $1)`, role).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return ErrRoleNotFound
+	}
+
+	query := `
+	UPDATE users
+	SET role = $1, updated_at = NOW(), version = version + 1
+	WHERE id = $2;`
+
+	result, err := pg.db.Exec(query, role, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user with ID %d not found", userID)
+	}
+	return nil
+}
+
+// SuspendUser suspends or bans the user and signs them out everywhere. It
+// returns sql.ErrNoRows when there is no such user.
+func (pg *PostgresUserStore) SuspendUser(userID int64, suspension Suspension) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = suspendUser(tx, userID, suspension); err != nil {
+		return err
+	}
 
+	return tx.Commit()
+}
 
+func (pg *PostgresUserStore) UnsuspendUser(userID int64) error {
+	query := `
+	UPDATE users
+	SET status = 'active', suspended_until = NULL, suspension_reason = NULL, updated_at = NOW(), version = version + 1
+	WHERE id = $1;`
+
+	return execOne(pg.db, query, userID)
+}
+
+// ActivateUser marks the user's email address as verified and removes
+// their activation tokens.
+func (pg *PostgresUserStore) ActivateUser(userID int64) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	UPDATE users
+	SET activated = true, updated_at = NOW(), version = version + 1
+	WHERE id = $1`
+
+	result, err := tx.Exec(query, userID)
+	if err != nil {
+		return err
+	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err = tx.Exec(`DELETE FROM tokens WHERE user_id = $1 AND scope = $2`, userID, tokens.ScopeActivation); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// suspendUser locks the account out and revokes all of its tokens, so
+// sessions that are already open end right away. It runs inside the
+// caller's transaction.
+func suspendUser(tx *sql.Tx, userID int64, suspension Suspension) error {
+	until := suspension.Until
+	if suspension.Status == UserStatusBanned {
+		until = nil
+	}
+
+	query := `
+	UPDATE users
+	SET status = $1, suspended_until = $2, suspension_reason = $3, updated_at = NOW(), version = version + 1
+	WHERE id = $4;`
+
+	result, err := tx.Exec(query, suspension.Status, until, suspension.Reason, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = tx.Exec(`DELETE FROM tokens WHERE user_id = $1`, userID)
+	return err
+}
@@ -10,6 +10,16 @@ import (
 const (
 	ScopeAuth = "authentication"
 	ScopePasswordReset = "password-reset"
+	ScopeActivation = "activation"
+	ScopeRefresh = "refresh"
+)
+
+const (
+	// AccessTokenTTL is how long an authentication token works. Clients
+	// keep the session going with a refresh token.
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is how long a session can go without being refreshed.
+	RefreshTokenTTL = 30 * 24 * time.Hour
 )
 
 type Token struct {
@@ -18,6 +28,11 @@ type Token struct {
 	UserID    int       `json:"-"`
 	Expiry    time.Time `json:"expiry"`
 	Scope     string    `json:"-"`
+	// UserAgent and IP describe the client the token was issued to.
+	UserAgent string `json:"-"`
+	IP        string `json:"-"`
+	// FamilyID groups the tokens issued by one login.
+	FamilyID string `json:"-"`
 }
 
 func GenerateToken(userID int, ttl time.Duration, scope string) (*Token, error) {
@@ -38,3 +53,28 @@ func GenerateToken(userID int, ttl time.Duration, scope string) (*Token, error)
 	token.Hash = hash[:]
 	return token, nil
 }
+
+// NewFamilyID returns a random id for a new token family.
+func NewFamilyID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+// APIKeyPrefix starts every API key so it can be told apart from a session
+// token, both by the server and by secret scanners.
+const APIKeyPrefix = "ak_"
+
+// GenerateAPIKey returns a new API key and the hash to store for it.
+func GenerateAPIKey() (string, []byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+
+	plaintext := APIKeyPrefix + base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+	hash := sha256.Sum256([]byte(plaintext))
+	return plaintext, hash[:], nil
+}
@@ -3,8 +3,13 @@ package utils
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -38,3 +43,119 @@ func ReadIDParam(r *http.Request) (int64, error) {
 	return id,nil
 }
 
+// ReadInt64Param reads a numeric URL parameter other than "id".
+func ReadInt64Param(r *http.Request, name string) (int64, error) {
+	param := chi.URLParam(r, name)
+	if param == "" {
+		return 0, fmt.Errorf("invalid %s parameter", name)
+	}
+	value, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s parameter type", name)
+	}
+
+	return value, nil
+}
+
+func ReadStringQuery(qs url.Values, key string, defaultValue string) string {
+	value := qs.Get(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+func ReadIntQuery(qs url.Values, key string, defaultValue int) (int, error) {
+	value := qs.Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer value", key)
+	}
+
+	return i, nil
+}
+
+func ReadBoolQuery(qs url.Values, key string, defaultValue bool) (bool, error) {
+	value := qs.Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean value", key)
+	}
+
+	return b, nil
+}
+
+// ReadTimeQuery accepts either a full RFC 3339 timestamp or a plain date.
+func ReadTimeQuery(qs url.Values, key string) (*time.Time, error) {
+	value := qs.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", key)
+}
+
+// SetETag advertises the version of the resource in the response so
+// clients can send it back in If-Match.
+func SetETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// IfMatch reports whether the request's If-Match precondition holds for the
+// given version. A request without If-Match always passes. If-Match uses the
+// strong comparison, so weak tags never match.
+func IfMatch(r *http.Request, version int) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+
+	etag := strconv.Quote(strconv.Itoa(version))
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WriteEditConflict answers a lost update. Clients that sent If-Match get
+// 412, everybody else a plain 409.
+func WriteEditConflict(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("If-Match") != "" {
+		WritePreconditionFailed(w)
+		return
+	}
+	WriteJSON(w, http.StatusConflict, Envelope{"error": "the resource was modified by someone else, please reload and try again"})
+}
+
+func WritePreconditionFailed(w http.ResponseWriter) {
+	WriteJSON(w, http.StatusPreconditionFailed, Envelope{"error": "the resource has been modified since you fetched it"})
+}
+
+// ClientIP returns the address of the client that made the request, without
+// the port. Forwarding headers are ignored since they can be spoofed.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		version int
+		want    bool
+	}{
+		{name: "no header", header: "", version: 3, want: true},
+		{name: "same version", header: `"3"`, version: 3, want: true},
+		{name: "weak etag", header: `W/"3"`, version: 3, want: false},
+		{name: "one of many", header: `"1", "3"`, version: 3, want: true},
+		{name: "wildcard", header: "*", version: 3, want: true},
+		{name: "stale version", header: `"2"`, version: 3, want: false},
+		{name: "unquoted", header: "3", version: 3, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/articles/1", nil)
+			if tt.header != "" {
+				r.Header.Set("If-Match", tt.header)
+			}
+			assert.Equal(t, tt.want, IfMatch(r, tt.version))
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/users/me/sessions", nil)
+	r.RemoteAddr = "203.0.113.7:51234"
+	assert.Equal(t, "203.0.113.7", ClientIP(r))
+
+	r.RemoteAddr = "[2001:db8::1]:443"
+	assert.Equal(t, "2001:db8::1", ClientIP(r))
+
+	r.RemoteAddr = "pipe"
+	assert.Equal(t, "pipe", ClientIP(r))
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/htojiddinov77-png/Articles/internal/app"
@@ -14,10 +19,20 @@ import (
 func main() {
 	
 	var port int 
+	var cfg app.Config
 	flag.IntVar(&port, "port", 8080, "go backend server port")
+	flag.DurationVar(&cfg.PublishInterval, "publish-interval", 30*time.Second, "how often scheduled articles are checked for publishing")
+	flag.DurationVar(&cfg.TrashRetention, "trash-retention", 30*24*time.Hour, "how long deleted articles and reviews are kept before they are purged")
+	flag.StringVar(&cfg.SMTP.Host, "smtp-host", "", "SMTP server host, emails are logged when empty")
+	flag.IntVar(&cfg.SMTP.Port, "smtp-port", 587, "SMTP server port")
+	flag.StringVar(&cfg.SMTP.Username, "smtp-username", "", "SMTP username")
+	flag.StringVar(&cfg.SMTP.Password, "smtp-password", "", "SMTP password")
+	flag.StringVar(&cfg.SMTP.Sender, "smtp-sender", "Articles <no-reply@articles.local>", "sender address of outgoing emails")
+	flag.StringVar(&cfg.BaseURL, "base-url", "http://localhost:8080", "public address of the API, used in links in emails")
+	flag.StringVar(&cfg.MailFile, "mail-file", "", "file outgoing emails are appended to when no SMTP host is set")
 	flag.Parse()
 
-	app, err := app.NewApplication()
+	app, err := app.NewApplication(cfg)
 	if err != nil{
 		panic(err)
 	}
@@ -30,12 +45,34 @@ func main() {
 		ReadTimeout: 10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		<-ctx.Done()
+
+		app.Logger.Printf("shutting down server")
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+		shutdownErr <- server.Shutdown(ctx)
+	}()
+
 	app.Logger.Printf("We are running on port %d", port)
 
 	err = server.ListenAndServe()
-	if err != nil{
+	if err != nil && !errors.Is(err, http.ErrServerClosed){
 		app.Logger.Fatal(err)
 	}
+
+	if err = <-shutdownErr; err != nil {
+		app.Logger.Printf("ERROR: server shutdown: %v", err)
+	}
+
+	if err = app.Close(); err != nil {
+		app.Logger.Printf("ERROR: closing application: %v", err)
+	}
+	app.Logger.Printf("server stopped")
 }
 func init() {
     time.Local = time.UTC